@@ -0,0 +1,88 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/anza-labs/kubelet-device-plugins/internal/entrypoint"
+	"github.com/anza-labs/kubelet-device-plugins/internal/logging"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/fusedeviceplugin"
+)
+
+var (
+	maxDevices  uint
+	propagation string
+	deviceMode  string
+	cdiSpecDir  string
+
+	logOpts logging.Options
+	opts    entrypoint.Options
+)
+
+func main() {
+	flag.UintVar(&maxDevices, "devices", 10, "Set number of devices presented to kubelet")
+	flag.StringVar(&propagation, "mount-propagation", "",
+		"Set mount propagation hinted to runtimes (HostToContainer, Bidirectional), disabled if empty")
+	flag.StringVar(&deviceMode, "device-mode", string(cdi.ModeDeviceSpec),
+		"Set how devices are passed to containers (device-spec, cdi, both)")
+	flag.StringVar(&cdiSpecDir, "cdi-spec-dir", cdi.DefaultSpecDir, "Set directory where CDI specs are written")
+	logOpts.AddFlags(flag.CommandLine)
+	opts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	log, level, err := logOpts.New(os.Stdout)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	opts.LogLevel = level
+
+	mode, err := cdi.ParseMode(deviceMode)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	prop, err := fusedeviceplugin.ParsePropagation(propagation)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	fuse := fusedeviceplugin.New(entrypoint.PluginNamespace, maxDevices, prop, cdi.Config{
+		Mode:    mode,
+		SpecDir: cdiSpecDir,
+	}, nil, log)
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	if err := entrypoint.Run(ctx, log, []entrypoint.Server{fuse}, nil, opts); err != nil {
+		log.Error("Critical failure", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/anza-labs/kubelet-device-plugins/internal/entrypoint"
+	"github.com/anza-labs/kubelet-device-plugins/internal/logging"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/drideviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/fusedeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/genericdeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/kvmdeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/loopdeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/serialdeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/tundeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/vfiodeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/vhostnetdeviceplugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/vhostvsockdeviceplugin"
+)
+
+var (
+	plugins          []string
+	maxDevices       uint
+	kvmDevices       uint
+	tunDevices       uint
+	vhostNetDevices  uint
+	fuseDevices      uint
+	kvmNested        bool
+	vsockCIDs        string
+	fusePropagation  string
+	loopPoolSize     uint
+	loopControl      bool
+	vfioAliases      []string
+	vfioDevRoot      string
+	sysfsRoot        string
+	serialConfigFile string
+	driDrivers       []string
+	driReplicas      uint
+	configFile       string
+	deviceMode       string
+	cdiSpecDir       string
+
+	logOpts logging.Options
+	opts    entrypoint.Options
+)
+
+// constructors create the servers of each plugin selectable with --plugins.
+var constructors = map[string]func(cdi.Config, *slog.Logger) ([]entrypoint.Server, error){
+	"kvm": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		kvm := kvmdeviceplugin.New(entrypoint.PluginNamespace, devices("kvm-devices", kvmDevices), cdiConfig, nil, log)
+		if !kvmNested {
+			return []entrypoint.Server{kvm}, nil
+		}
+		if opts.PodResourcesInterval <= 0 {
+			return nil, errors.New("--kvm-nested requires --pod-resources-interval")
+		}
+		return []entrypoint.Server{kvm, kvm.Nested()}, nil
+	},
+	"tun": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		return []entrypoint.Server{
+			tundeviceplugin.New(entrypoint.PluginNamespace, devices("tun-devices", tunDevices), cdiConfig, nil, log),
+		}, nil
+	},
+	"vhost-net": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		return []entrypoint.Server{
+			vhostnetdeviceplugin.New(entrypoint.PluginNamespace, devices("vhost-net-devices", vhostNetDevices),
+				cdiConfig, nil, log),
+		}, nil
+	},
+	"vhost-vsock": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		cids, err := vhostvsockdeviceplugin.ParseCIDRange(vsockCIDs)
+		if err != nil {
+			return nil, err
+		}
+		return []entrypoint.Server{
+			vhostvsockdeviceplugin.New(entrypoint.PluginNamespace, cids, cdiConfig, nil, log),
+		}, nil
+	},
+	"fuse": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		prop, err := fusedeviceplugin.ParsePropagation(fusePropagation)
+		if err != nil {
+			return nil, err
+		}
+		return []entrypoint.Server{
+			fusedeviceplugin.New(entrypoint.PluginNamespace, devices("fuse-devices", fuseDevices), prop,
+				cdiConfig, nil, log),
+		}, nil
+	},
+	"loop": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		return []entrypoint.Server{
+			loopdeviceplugin.New(entrypoint.PluginNamespace, loopPoolSize, loopControl, cdiConfig, nil, log),
+		}, nil
+	},
+	"vfio": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		aliases, err := vfiodeviceplugin.ParseAliases(vfioAliases)
+		if err != nil {
+			return nil, err
+		}
+		vfio, err := vfiodeviceplugin.Servers(entrypoint.PluginNamespace, sysfsRoot, vfioDevRoot, aliases, cdiConfig, log)
+		if err != nil {
+			return nil, err
+		}
+		servers := make([]entrypoint.Server, 0, len(vfio))
+		for _, s := range vfio {
+			servers = append(servers, s)
+		}
+		return servers, nil
+	},
+	"serial": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		cfg, err := serialdeviceplugin.LoadConfig(serialConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		servers := make([]entrypoint.Server, 0, len(cfg.Resources))
+		for _, s := range serialdeviceplugin.Servers(entrypoint.PluginNamespace, cfg, sysfsRoot, cdiConfig, log) {
+			servers = append(servers, s)
+		}
+		return servers, nil
+	},
+	"dri": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		dri, err := drideviceplugin.Servers(entrypoint.PluginNamespace, sysfsRoot, driDrivers, driReplicas, cdiConfig, log)
+		if err != nil {
+			return nil, err
+		}
+		servers := make([]entrypoint.Server, 0, len(dri))
+		for _, s := range dri {
+			servers = append(servers, s)
+		}
+		return servers, nil
+	},
+	"generic": func(cdiConfig cdi.Config, log *slog.Logger) ([]entrypoint.Server, error) {
+		cfg, err := genericdeviceplugin.LoadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		servers := make([]entrypoint.Server, 0, len(cfg.Groups))
+		for _, group := range cfg.Groups {
+			servers = append(servers, genericdeviceplugin.New(entrypoint.PluginNamespace, group, cdiConfig, nil, log))
+		}
+		return servers, nil
+	},
+}
+
+// devices returns the number of devices of a plugin, set by its flag or by --devices.
+func devices(name string, n uint) uint {
+	if flag.CommandLine.Changed(name) {
+		return n
+	}
+	return maxDevices
+}
+
+func main() {
+	flag.StringSliceVar(&plugins, "plugins", []string{"kvm", "tun"},
+		fmt.Sprintf("Set plugins served by this process (%s)", strings.Join(slices.Sorted(maps.Keys(constructors)), ", ")))
+	flag.UintVar(&maxDevices, "devices", 10,
+		"Set number of devices presented to kubelet by the kvm, tun, vhost-net and fuse plugins")
+	flag.UintVar(&kvmDevices, "kvm-devices", 0,
+		"Set number of devices presented to kubelet by the kvm plugin, overriding --devices")
+	flag.UintVar(&tunDevices, "tun-devices", 0,
+		"Set number of devices presented to kubelet by the tun plugin, overriding --devices")
+	flag.UintVar(&vhostNetDevices, "vhost-net-devices", 0,
+		"Set number of devices presented to kubelet by the vhost-net plugin, overriding --devices")
+	flag.UintVar(&fuseDevices, "fuse-devices", 0,
+		"Set number of devices presented to kubelet by the fuse plugin, overriding --devices")
+	flag.StringVar(&vsockCIDs, "vsock-cid-range", vhostvsockdeviceplugin.DefaultCIDRange.String(),
+		"Set range of guest CIDs presented to kubelet by the vhost-vsock plugin (first-last)")
+	flag.StringVar(&fusePropagation, "fuse-mount-propagation", "",
+		"Set mount propagation hinted to runtimes by the fuse plugin (HostToContainer, Bidirectional)")
+	flag.UintVar(&loopPoolSize, "loop-pool-size", 0,
+		"Set number of loop devices created by the loop plugin if missing, disabled if 0")
+	flag.BoolVar(&loopControl, "loop-control", false, "Pass /dev/loop-control to containers along with loop devices")
+	flag.StringSliceVar(&vfioAliases, "vfio-alias", nil,
+		"Set resource name of devices with the PCI ID served by the vfio plugin (e.g. 8086:1572=x710)")
+	flag.StringVar(&vfioDevRoot, "vfio-dev-root", vfiodeviceplugin.DefaultDevRoot,
+		"Set path where the host /dev is mounted, device nodes of the vfio plugin are checked below it")
+	flag.StringVar(&sysfsRoot, "sysfs-root", vfiodeviceplugin.DefaultSysfsRoot, "Set path where sysfs is mounted")
+	flag.StringVar(&serialConfigFile, "serial-config", "/etc/serial-device-plugin/config.yaml",
+		"Set path to the configuration file of the serial plugin")
+	flag.StringSliceVar(&driDrivers, "dri-drivers", nil,
+		"Set drivers served by the dri plugin even if no GPU is bound to them at startup")
+	flag.UintVar(&driReplicas, "dri-replicas", 1, "Set number of containers sharing each render node")
+	flag.BoolVar(&kvmNested, "kvm-nested", false,
+		"Advertise kvm-nested on nodes with nested virtualization, sharing --kvm-devices with kvm")
+	flag.StringVar(&configFile, "config", "/etc/generic-device-plugin/config.yaml",
+		"Set path to the configuration file of the generic plugin")
+	flag.StringVar(&deviceMode, "device-mode", string(cdi.ModeDeviceSpec),
+		"Set how devices are passed to containers (device-spec, cdi, both)")
+	flag.StringVar(&cdiSpecDir, "cdi-spec-dir", cdi.DefaultSpecDir, "Set directory where CDI specs are written")
+	logOpts.AddFlags(flag.CommandLine)
+	opts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	log, level, err := logOpts.New(os.Stdout)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	opts.LogLevel = level
+
+	mode, err := cdi.ParseMode(deviceMode)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	cdiConfig := cdi.Config{
+		Mode:    mode,
+		SpecDir: cdiSpecDir,
+	}
+
+	servers := []entrypoint.Server{}
+	for _, name := range slices.Compact(slices.Sorted(slices.Values(plugins))) {
+		newServers, ok := constructors[name]
+		if !ok {
+			log.Error("Invalid configuration", "error", fmt.Errorf("unknown plugin %q", name))
+			os.Exit(1)
+		}
+
+		s, err := newServers(cdiConfig, log.With("plugin", name))
+		if err != nil {
+			log.Error("Invalid configuration", "plugin", name, "error", err)
+			os.Exit(1)
+		}
+		servers = append(servers, s...)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	if err := entrypoint.Run(ctx, log, servers, nil, opts); err != nil {
+		log.Error("Critical failure", "error", err)
+		os.Exit(1)
+	}
+}
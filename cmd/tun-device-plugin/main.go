@@ -24,35 +24,46 @@ import (
 	flag "github.com/spf13/pflag"
 
 	"github.com/anza-labs/kubelet-device-plugins/internal/entrypoint"
+	"github.com/anza-labs/kubelet-device-plugins/internal/logging"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
 	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/tundeviceplugin"
 )
 
 var (
-	logLevel   string
 	maxDevices uint
+	deviceMode string
+	cdiSpecDir string
+
+	logOpts logging.Options
+	opts    entrypoint.Options
 )
 
 func main() {
-	flag.StringVar(&logLevel, "log-level", "info", "Set log level (debug, info, warn, error)")
 	flag.UintVar(&maxDevices, "devices", 10, "Set number of devices presented to kubelet")
+	flag.StringVar(&deviceMode, "device-mode", string(cdi.ModeDeviceSpec),
+		"Set how devices are passed to containers (device-spec, cdi, both)")
+	flag.StringVar(&cdiSpecDir, "cdi-spec-dir", cdi.DefaultSpecDir, "Set directory where CDI specs are written")
+	logOpts.AddFlags(flag.CommandLine)
+	opts.AddFlags(flag.CommandLine)
 	flag.Parse()
 
-	var level slog.Level
-	switch logLevel {
-	case "debug":
-		level = slog.LevelDebug
-	case "info":
-		level = slog.LevelInfo
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo // Default to info if unknown
+	log, level, err := logOpts.New(os.Stdout)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	opts.LogLevel = level
+
+	mode, err := cdi.ParseMode(deviceMode)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
 
-	tun := tundeviceplugin.New(entrypoint.PluginNamespace, maxDevices, log)
+	tun := tundeviceplugin.New(entrypoint.PluginNamespace, maxDevices, cdi.Config{
+		Mode:    mode,
+		SpecDir: cdiSpecDir,
+	}, nil, log)
 
 	ctx, stop := signal.NotifyContext(context.Background(),
 		os.Interrupt,
@@ -61,7 +72,7 @@ func main() {
 	)
 	defer stop()
 
-	if err := entrypoint.Run(ctx, log, tun, nil); err != nil {
+	if err := entrypoint.Run(ctx, log, []entrypoint.Server{tun}, nil, opts); err != nil {
 		log.Error("Critical failure", "error", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,100 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/anza-labs/kubelet-device-plugins/internal/entrypoint"
+	"github.com/anza-labs/kubelet-device-plugins/internal/logging"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/servers/vfiodeviceplugin"
+)
+
+var (
+	sysfsRoot  string
+	devRoot    string
+	aliases    []string
+	deviceMode string
+	cdiSpecDir string
+
+	logOpts logging.Options
+	opts    entrypoint.Options
+)
+
+func main() {
+	flag.StringVar(&sysfsRoot, "sysfs-root", vfiodeviceplugin.DefaultSysfsRoot, "Set path where sysfs is mounted")
+	flag.StringVar(&devRoot, "dev-root", vfiodeviceplugin.DefaultDevRoot,
+		"Set path where the host /dev is mounted, device nodes are checked below it")
+	flag.StringSliceVar(&aliases, "alias", nil,
+		"Set resource name of devices with the PCI ID, in the <vendor>:<device>=<name> form (e.g. 8086:1572=x710)")
+	flag.StringVar(&deviceMode, "device-mode", string(cdi.ModeDeviceSpec),
+		"Set how devices are passed to containers (device-spec, cdi, both)")
+	flag.StringVar(&cdiSpecDir, "cdi-spec-dir", cdi.DefaultSpecDir, "Set directory where CDI specs are written")
+	logOpts.AddFlags(flag.CommandLine)
+	opts.AddFlags(flag.CommandLine)
+	flag.Parse()
+
+	log, level, err := logOpts.New(os.Stdout)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	opts.LogLevel = level
+
+	mode, err := cdi.ParseMode(deviceMode)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	names, err := vfiodeviceplugin.ParseAliases(aliases)
+	if err != nil {
+		log.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	vfio, err := vfiodeviceplugin.Servers(entrypoint.PluginNamespace, sysfsRoot, devRoot, names, cdi.Config{
+		Mode:    mode,
+		SpecDir: cdiSpecDir,
+	}, log)
+	if err != nil {
+		log.Error("Failed to discover IOMMU groups", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(),
+		os.Interrupt,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	servers := make([]entrypoint.Server, 0, len(vfio))
+	for _, s := range vfio {
+		servers = append(servers, s)
+	}
+
+	if err := entrypoint.Run(ctx, log, servers, nil, opts); err != nil {
+		log.Error("Critical failure", "error", err)
+		os.Exit(1)
+	}
+}
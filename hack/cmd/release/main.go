@@ -32,6 +32,33 @@ const (
 	defaultKvmPluginImageRef  = "ghcr.io/anza-labs/kvm-device-plugin"
 	defaultTunPluginImageName = "tun"
 	defaultTunPluginImageRef  = "ghcr.io/anza-labs/tun-device-plugin"
+
+	defaultVhostNetPluginImageName = "vhost-net"
+	defaultVhostNetPluginImageRef  = "ghcr.io/anza-labs/vhost-net-device-plugin"
+
+	defaultVhostVsockPluginImageName = "vhost-vsock"
+	defaultVhostVsockPluginImageRef  = "ghcr.io/anza-labs/vhost-vsock-device-plugin"
+
+	defaultFusePluginImageName = "fuse"
+	defaultFusePluginImageRef  = "ghcr.io/anza-labs/fuse-device-plugin"
+
+	defaultLoopPluginImageName = "loop"
+	defaultLoopPluginImageRef  = "ghcr.io/anza-labs/loop-device-plugin"
+
+	defaultVfioPluginImageName = "vfio"
+	defaultVfioPluginImageRef  = "ghcr.io/anza-labs/vfio-device-plugin"
+
+	defaultDriPluginImageName = "dri"
+	defaultDriPluginImageRef  = "ghcr.io/anza-labs/dri-device-plugin"
+
+	defaultGenericPluginImageName = "generic"
+	defaultGenericPluginImageRef  = "ghcr.io/anza-labs/generic-device-plugin"
+
+	defaultSerialPluginImageName = "serial"
+	defaultSerialPluginImageRef  = "ghcr.io/anza-labs/serial-device-plugin"
+
+	defaultCombinedImageName = "kubelet-device-plugins"
+	defaultCombinedImageRef  = "ghcr.io/anza-labs/kubelet-device-plugins"
 )
 
 func runCommand(name string, args ...string) error {
@@ -140,10 +167,31 @@ func main() {
 	newKvmImageFlag := flag.String("kvm-plugin-image", defaultKvmPluginImageRef, "Default image reference")
 	tunImageFlag := flag.String("tun-plugin-image-name", defaultTunPluginImageName, "Default image name")
 	newTunImageFlag := flag.String("tun-plugin-image", defaultTunPluginImageRef, "Default image reference")
+	vhostNetImageFlag := flag.String("vhost-net-plugin-image-name", defaultVhostNetPluginImageName, "Default image name")
+	newVhostNetImageFlag := flag.String("vhost-net-plugin-image", defaultVhostNetPluginImageRef, "Default image reference")
+	vhostVsockImageFlag := flag.String("vhost-vsock-plugin-image-name", defaultVhostVsockPluginImageName,
+		"Default image name")
+	newVhostVsockImageFlag := flag.String("vhost-vsock-plugin-image", defaultVhostVsockPluginImageRef,
+		"Default image reference")
+	fuseImageFlag := flag.String("fuse-plugin-image-name", defaultFusePluginImageName, "Default image name")
+	newFuseImageFlag := flag.String("fuse-plugin-image", defaultFusePluginImageRef, "Default image reference")
+	loopImageFlag := flag.String("loop-plugin-image-name", defaultLoopPluginImageName, "Default image name")
+	newLoopImageFlag := flag.String("loop-plugin-image", defaultLoopPluginImageRef, "Default image reference")
+	vfioImageFlag := flag.String("vfio-plugin-image-name", defaultVfioPluginImageName, "Default image name")
+	newVfioImageFlag := flag.String("vfio-plugin-image", defaultVfioPluginImageRef, "Default image reference")
+	driImageFlag := flag.String("dri-plugin-image-name", defaultDriPluginImageName, "Default image name")
+	newDriImageFlag := flag.String("dri-plugin-image", defaultDriPluginImageRef, "Default image reference")
+	genericImageFlag := flag.String("generic-plugin-image-name", defaultGenericPluginImageName, "Default image name")
+	newGenericImageFlag := flag.String("generic-plugin-image", defaultGenericPluginImageRef, "Default image reference")
+	serialImageFlag := flag.String("serial-plugin-image-name", defaultSerialPluginImageName, "Default image name")
+	newSerialImageFlag := flag.String("serial-plugin-image", defaultSerialPluginImageRef, "Default image reference")
+	combinedImageFlag := flag.String("combined-image-name", defaultCombinedImageName, "Default image name")
+	newCombinedImageFlag := flag.String("combined-image", defaultCombinedImageRef, "Default image reference")
 
 	flag.Parse()
 
 	resources := []string{"./config/plugin", "./config/rbac"}
+	combinedResources := []string{"../config/combined", "../config/rbac"}
 
 	version, err := parseVersion(*versionFlag)
 	if err != nil {
@@ -165,11 +213,66 @@ func main() {
 			"newName": *newTunImageFlag,
 			"newTag":  *versionFlag,
 		},
+		{
+			"name":    *vhostNetImageFlag,
+			"newName": *newVhostNetImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *vhostVsockImageFlag,
+			"newName": *newVhostVsockImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *fuseImageFlag,
+			"newName": *newFuseImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *loopImageFlag,
+			"newName": *newLoopImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *vfioImageFlag,
+			"newName": *newVfioImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *driImageFlag,
+			"newName": *newDriImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *genericImageFlag,
+			"newName": *newGenericImageFlag,
+			"newTag":  *versionFlag,
+		},
+		{
+			"name":    *serialImageFlag,
+			"newName": *newSerialImageFlag,
+			"newTag":  *versionFlag,
+		},
 	})
 	if err := writeKustomization(kustomization, "./kustomization.yaml"); err != nil {
 		log.Fatalf("Failed to write kustomization: %v", err)
 	}
 
+	// all plugins served from a single DaemonSet, deployed instead of the kustomization above
+	combined := createKustomization(combinedResources, []map[string]string{
+		{
+			"name":    *combinedImageFlag,
+			"newName": *newCombinedImageFlag,
+			"newTag":  *versionFlag,
+		},
+	})
+	if err := os.MkdirAll("./combined", 0755); err != nil {
+		log.Fatalf("Failed to create combined kustomization directory: %v", err)
+	}
+	if err := writeKustomization(combined, "./combined/kustomization.yaml"); err != nil {
+		log.Fatalf("Failed to write combined kustomization: %v", err)
+	}
+
 	if err := release(version, *versionFlag); err != nil {
 		log.Fatalf("Failed to release: %v", err)
 	}
@@ -18,29 +18,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/anza-labs/kubelet-device-plugins/internal/logging"
 	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/events"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
 	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
 	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/podresources"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/secureserving"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
 
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
 const (
-	PluginNamespace = "devices.anza-labs.dev"
-	gracePeriod     = 5 * time.Second
+	PluginNamespace        = "devices.anza-labs.dev"
+	DefaultMetricsAddress  = "tcp://0.0.0.0:8080"
+	DefaultLogLevelAddress = "tcp://127.0.0.1:8081"
+	gracePeriod            = 5 * time.Second
+	registerBaseDelay      = time.Second
+	registerMaxDelay       = time.Minute
+	readHeaderTimeout      = 10 * time.Second
 )
 
 type Server interface {
@@ -49,72 +64,171 @@ type Server interface {
 	Socket() string
 }
 
+// EventSource is implemented by servers that emit events on changes of their devices.
+type EventSource interface {
+	SetEventRecorder(rec *events.Recorder)
+}
+
+// FeatureSource is implemented by servers that publish features of their devices
+// as node labels.
+type FeatureSource interface {
+	features.Source
+	SetFeatureFile(f *features.File)
+}
+
 type HealthServer interface {
 	grpc_health_v1.HealthServer
 	SetServingStatus(service string, servingStatus grpc_health_v1.HealthCheckResponse_ServingStatus)
 }
 
+// Run serves all device plugin servers in a single process. Every server listens on
+// its own socket and registers with kubelet separately, while the metrics endpoint and
+// the health server are shared. Without any device plugin servers, Run only serves the
+// health server on /health.sock.
 func Run(
 	ctx context.Context,
 	log *slog.Logger,
-	devicePluginServer Server,
+	devicePluginServers []Server,
 	healthServer HealthServer,
+	opts Options,
 ) error {
 	log.Info("Starting plugin")
-	eg, ctx := errgroup.WithContext(ctx)
 
-	dps := plugin.New(log)
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	if err := validateServers(devicePluginServers); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, opts.TracingEndpoint, filepath.Base(os.Args[0]))
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		dctx, stop := context.WithTimeout(context.Background(), gracePeriod)
+		defer stop()
+
+		if err := shutdownTracing(dctx); err != nil {
+			log.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	eg, ctx := errgroup.WithContext(ctx)
 
-	grpcServer := dps.DevicePluginServer(devicePluginServer)
-	httpServer := metricsServer()
+	handlers := map[string]http.Handler{}
+	grpcServers := make([]*grpc.Server, 0, len(devicePluginServers))
 
 	if healthServer == nil {
 		healthServer = health.NewServer()
 	}
-	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 
-	eg.Go(func() error {
-		log.Info("Starting shutdown controller")
-		return shutdown(ctx, log, grpcServer, httpServer)
-	})
+	var recorder *events.Recorder
+	if len(devicePluginServers) > 0 && opts.NodeName != "" {
+		client, err := opts.kubeClient()
+		if err != nil {
+			return fmt.Errorf("failed to configure events: %w", err)
+		}
+
+		recorder = events.New(client, opts.NodeName, log)
+		defer recorder.Shutdown()
+
+		for _, devicePluginServer := range devicePluginServers {
+			if es, ok := devicePluginServer.(EventSource); ok {
+				es.SetEventRecorder(recorder)
+			}
+		}
+	}
+
+	if opts.FeaturesDir != "" {
+		for _, devicePluginServer := range devicePluginServers {
+			if fs, ok := devicePluginServer.(FeatureSource); ok {
+				fs.SetFeatureFile(features.NewFile(opts.FeaturesDir, devicePluginServer.Name()))
+			}
+		}
+	}
+
+	var levelHandler http.Handler
+	if opts.LogLevel != nil {
+		levelHandler = logging.NewLevelHandler(opts.LogLevel, log)
+		// the log level can only be changed on the metrics endpoint by authorized clients
+		if opts.MetricsAuth {
+			handlers["/loglevel"] = levelHandler
+		}
+	}
+
+	if len(devicePluginServers) > 0 && opts.PodResourcesInterval > 0 {
+		resources := make([]string, 0, len(devicePluginServers))
+		for _, devicePluginServer := range devicePluginServers {
+			resources = append(resources, devicePluginServer.Name())
+		}
+
+		reconciler := podresources.New(opts.PodResourcesSocket, opts.PodResourcesInterval, resources, log)
+		handlers["/allocations"] = reconciler
+		for _, devicePluginServer := range devicePluginServers {
+			if sub, ok := devicePluginServer.(podresources.Subscriber); ok {
+				reconciler.Subscribe(sub)
+			}
+		}
 
-	if devicePluginServer != nil {
 		eg.Go(func() error {
-			log.Info("Registering device plugin")
-			return dps.RegisterDevicePlugin(ctx, devicePluginServer.Name(), devicePluginServer.Socket())
+			log.Info("Starting pod resources reconciler")
+			return reconciler.Run(ctx)
 		})
+	}
 
-		eg.Go(func() error {
-			lis, cleanup, err := listener(ctx, log, "tcp://0.0.0.0:8080")
+	var httpServers []*http.Server
+
+	if len(devicePluginServers) > 0 {
+		if opts.MetricsAddress != "" {
+			httpServer, err := metricsServer(log, opts, handlers)
 			if err != nil {
-				return fmt.Errorf("failed to create http listener: %w", err)
+				return fmt.Errorf("failed to configure metrics endpoint: %w", err)
 			}
-			defer cleanup()
+			httpServers = append(httpServers, httpServer)
 
-			log.Info("Starting HTTP server")
-			return httpServer.Serve(lis)
-		})
+			eg.Go(func() error {
+				return serveHTTP(ctx, log, httpServer, opts.MetricsAddress)
+			})
+		}
 
-		eg.Go(func() error {
-			lis, cleanup, err := listener(ctx, log, devicePluginServer.Socket())
-			if err != nil {
-				return fmt.Errorf("failed to create grpc listener: %w", err)
+		if levelHandler != nil && opts.LogLevelEndpoint {
+			mux := http.NewServeMux()
+			mux.Handle("/loglevel", levelHandler)
+			httpServer := &http.Server{
+				Handler:           mux,
+				ReadHeaderTimeout: readHeaderTimeout,
 			}
-			defer cleanup()
+			httpServers = append(httpServers, httpServer)
 
-			// Mark server as healthy
-			healthServer.SetServingStatus(devicePluginServer.Name(), grpc_health_v1.HealthCheckResponse_SERVING)
+			eg.Go(func() error {
+				return serveHTTP(ctx, log, httpServer, opts.LogLevelAddress)
+			})
+		}
 
-			log.Info("Starting gRPC server")
-			return grpcServer.Serve(lis)
-		})
+		for _, devicePluginServer := range devicePluginServers {
+			log := log.With("resource", devicePluginServer.Name())
+			dps := plugin.New(log)
+
+			grpcServer := dps.DevicePluginServer(devicePluginServer)
+			grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+			grpcServers = append(grpcServers, grpcServer)
 
-		if du, ok := devicePluginServer.(discovery.DiscoverUpdater); ok {
 			eg.Go(func() error {
-				return discovery.Discovery(ctx, log, du)
+				return serve(ctx, log, dps, grpcServer, healthServer, recorder, devicePluginServer)
 			})
+
+			if du, ok := devicePluginServer.(discovery.DiscoverUpdater); ok {
+				eg.Go(func() error {
+					return discovery.Discovery(ctx, log, du, opts.ResyncInterval)
+				})
+			}
 		}
 	} else {
+		grpcServer := plugin.New(log).DevicePluginServer(nil)
+		grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+		grpcServers = append(grpcServers, grpcServer)
+
 		eg.Go(func() error {
 			lis, cleanup, err := listener(ctx, log, fmt.Sprintf("unix://%s", "/health.sock"))
 			if err != nil {
@@ -127,10 +241,153 @@ func Run(
 		})
 	}
 
+	eg.Go(func() error {
+		log.Info("Starting shutdown controller")
+		return shutdown(ctx, log, devicePluginServers, grpcServers, httpServers)
+	})
+
 	log.Info("Plugin is running")
 	return eg.Wait()
 }
 
+// serve runs the gRPC server on the plugin socket and registers the plugin with kubelet.
+// Whenever kubelet restarts or the plugin socket is removed, the listener is recreated
+// and the plugin is registered again.
+func serve(
+	ctx context.Context,
+	log *slog.Logger,
+	dps *plugin.Plugin,
+	grpcServer *grpc.Server,
+	healthServer HealthServer,
+	recorder *events.Recorder,
+	devicePluginServer Server,
+) error {
+	name, socket := devicePluginServer.Name(), devicePluginServer.Socket()
+	metrics.Registered.WithLabelValues(name).Set(0)
+
+	restart, err := dps.WatchKubelet(ctx, socket)
+	if err != nil {
+		return fmt.Errorf("failed to watch kubelet socket: %w", err)
+	}
+
+	reason := plugin.ReasonStartup
+	for {
+		lis, cleanup, err := listener(ctx, log, socket)
+		if err != nil {
+			return fmt.Errorf("failed to create grpc listener: %w", err)
+		}
+
+		serveErr := make(chan error, 1)
+		go func() {
+			log.Info("Starting gRPC server")
+			serveErr <- grpcServer.Serve(lis)
+		}()
+
+		// Mark server as healthy
+		healthServer.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_SERVING)
+
+		if err := dps.SetSocket(socket); err != nil {
+			log.Error("Failed to record plugin socket", "error", err)
+		}
+
+		backoff := registerBaseDelay
+	registration:
+		for {
+			var timer *time.Timer
+
+			log.Info("Registering device plugin", "reason", reason)
+			if err := register(ctx, dps, name, socket, reason); err != nil {
+				// kubelet may be restarting, retry until it accepts the registration or kubelet.sock is recreated
+				log.Error("Failed to register device plugin, retrying", "backoff", backoff, "error", err)
+				metrics.Registrations.WithLabelValues(name, reason, "failure").Inc()
+				recorder.Warning(events.ReasonRegistrationFailed,
+					"Failed to register %s with kubelet (%s): %v", name, reason, err)
+
+				timer = time.NewTimer(backoff)
+				backoff = min(2*backoff, registerMaxDelay)
+			} else {
+				metrics.Registrations.WithLabelValues(name, reason, "success").Inc()
+				metrics.Registered.WithLabelValues(name).Set(1)
+				recorder.Normal(events.ReasonRegistered, "Registered %s with kubelet (%s)", name, reason)
+			}
+
+			select {
+			case <-ctx.Done():
+				stop(timer)
+				err := <-serveErr
+				dps.ClearSocket()
+				cleanup()
+				return err
+
+			case err := <-serveErr:
+				stop(timer)
+				dps.ClearSocket()
+				cleanup()
+				return err
+
+			case <-expired(timer):
+
+			case reason = <-restart:
+				stop(timer)
+				log.Info("Restarting gRPC server", "reason", reason)
+				metrics.Registered.WithLabelValues(name).Set(0)
+				dps.ClearSocket()
+				cleanup()
+				<-serveErr
+				break registration
+			}
+		}
+	}
+}
+
+// validateServers checks that no two servers serve the same resource or socket, as
+// kubelet would replace the registration of one with the other.
+func validateServers(servers []Server) error {
+	names := map[string]struct{}{}
+	sockets := map[string]string{}
+	for _, s := range servers {
+		name, socket := s.Name(), s.Socket()
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("resource %s is served more than once", name)
+		}
+		names[name] = struct{}{}
+
+		if other, ok := sockets[socket]; ok {
+			return fmt.Errorf("resources %s and %s are served on the same socket %s", other, name, socket)
+		}
+		sockets[socket] = name
+	}
+	return nil
+}
+
+// expired returns the channel of the registration retry timer, or nil (blocking forever)
+// when no retry is pending.
+func expired(timer *time.Timer) <-chan time.Time {
+	if timer == nil {
+		return nil
+	}
+	return timer.C
+}
+
+// stop stops the registration retry timer, if any.
+func stop(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// register registers the plugin with kubelet, traced as a span with the reason of the registration.
+func register(ctx context.Context, dps *plugin.Plugin, name, socket, reason string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "Register", trace.WithAttributes(
+		tracing.ResourceKey.String(name),
+		tracing.ReasonKey.String(reason),
+	))
+
+	err := dps.RegisterDevicePlugin(ctx, name, socket)
+	tracing.End(span, err)
+	return err
+}
+
 func listener(
 	ctx context.Context,
 	log *slog.Logger,
@@ -148,7 +405,12 @@ func listener(
 		_ = os.Remove(endpointURL.Path)
 	}
 
-	listener, err := listenConfig.Listen(ctx, endpointURL.Scheme, endpointURL.Path)
+	address := endpointURL.Path
+	if endpointURL.Scheme != "unix" {
+		address = endpointURL.Host
+	}
+
+	listener, err := listenConfig.Listen(ctx, endpointURL.Scheme, address)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create listener: %w", err)
 	}
@@ -161,7 +423,8 @@ func listener(
 		}
 
 		if endpointURL.Scheme == "unix" {
-			if err := os.Remove(endpointURL.Path); err != nil {
+			// closing the listener usually unlinks the socket already
+			if err := os.Remove(endpointURL.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
 				log.Error("Failed to remove old socket", "error", err)
 			}
 		}
@@ -170,26 +433,85 @@ func listener(
 	return listener, cleanup, nil
 }
 
-func metricsServer() *http.Server {
+// serveHTTP runs the HTTP server on address until it is shut down.
+func serveHTTP(ctx context.Context, log *slog.Logger, httpServer *http.Server, address string) error {
+	lis, cleanup, err := listener(ctx, log, address)
+	if err != nil {
+		return fmt.Errorf("failed to create http listener: %w", err)
+	}
+	defer cleanup()
+
+	if httpServer.TLSConfig != nil {
+		log.Info("Starting HTTPS server", "address", address)
+		err = httpServer.ServeTLS(lis, "", "")
+	} else {
+		log.Info("Starting HTTP server", "address", address)
+		err = httpServer.Serve(lis)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// metricsServer creates the HTTP server of the metrics endpoint, optionally protected by
+// TLS and Kubernetes authentication and authorization.
+func metricsServer(log *slog.Logger, opts Options, handlers map[string]http.Handler) (*http.Server, error) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
-	return &http.Server{Handler: mux}
+	for pattern, handler := range handlers {
+		mux.Handle(pattern, handler)
+	}
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	if opts.MetricsAuth {
+		client, err := opts.kubeClient()
+		if err != nil {
+			return nil, err
+		}
+		srv.Handler = secureserving.NewAuthorizer(client, secureserving.DefaultCacheTTL, log).Wrap(mux)
+	}
+
+	if opts.MetricsCertFile != "" || opts.MetricsKeyFile != "" {
+		certs, err := secureserving.NewCertReloader(opts.MetricsCertFile, opts.MetricsKeyFile, log)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig = certs.TLSConfig()
+	}
+
+	return srv, nil
 }
 
 func shutdown(
 	ctx context.Context,
 	log *slog.Logger,
-	grpcServer *grpc.Server,
-	httpServer *http.Server,
+	devicePluginServers []Server,
+	grpcServers []*grpc.Server,
+	httpServers []*http.Server,
 ) error {
 	<-ctx.Done()
 	log.Info("Shutting down")
+
+	// End long-lived ListAndWatch streams, so that gRPC servers can stop gracefully
+	for _, devicePluginServer := range devicePluginServers {
+		if c, ok := devicePluginServer.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Error("Failed to close device plugin server", "resource", devicePluginServer.Name(), "error", err)
+			}
+		}
+	}
+
 	dctx, stop := context.WithTimeout(context.Background(), gracePeriod)
 	defer stop()
 
 	eg, dctx := errgroup.WithContext(dctx)
 
-	if grpcServer != nil {
+	for _, grpcServer := range grpcServers {
 		eg.Go(func() error {
 			log.Debug("Shutting down gRPC server")
 
@@ -212,7 +534,7 @@ func shutdown(
 		})
 	}
 
-	if httpServer != nil {
+	for _, httpServer := range httpServers {
 		eg.Go(func() error {
 			log.Debug("Shutting down HTTP server")
 
@@ -0,0 +1,269 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entrypoint
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+type server struct {
+	plugin.Base
+
+	name, socket string
+}
+
+func newServer(name, socket string) *server {
+	return &server{Base: plugin.NewBase(name, cdi.Config{}, nil), name: name, socket: socket}
+}
+
+func (s *server) Name() string   { return s.name }
+func (s *server) Socket() string { return s.socket }
+
+func (s *server) Allocate(context.Context, *v1beta1.AllocateRequest) (*v1beta1.AllocateResponse, error) {
+	return &v1beta1.AllocateResponse{}, nil
+}
+
+func TestValidateServers(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		servers []Server
+		err     string
+	}{
+		{
+			name: "distinct",
+			servers: []Server{
+				newServer("example.com/a", "unix:///a.sock"),
+				newServer("example.com/b", "unix:///b.sock"),
+			},
+		},
+		{
+			name: "duplicate name",
+			servers: []Server{
+				newServer("example.com/a", "unix:///a.sock"),
+				newServer("example.com/a", "unix:///b.sock"),
+			},
+			err: "served more than once",
+		},
+		{
+			name: "duplicate socket",
+			servers: []Server{
+				newServer("example.com/a", "unix:///a.sock"),
+				newServer("example.com/b", "unix:///a.sock"),
+			},
+			err: "same socket",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateServers(tc.servers)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("validateServers() = %v, want nil", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("validateServers() = %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+// kubelet is a fake kubelet registration service.
+type kubelet struct {
+	v1beta1.UnimplementedRegistrationServer
+
+	registered chan string
+}
+
+func (k *kubelet) Register(_ context.Context, req *v1beta1.RegisterRequest) (*v1beta1.Empty, error) {
+	k.registered <- req.ResourceName
+	return &v1beta1.Empty{}, nil
+}
+
+// startKubelet serves the fake kubelet registration service on socket.
+func startKubelet(t *testing.T, socket string) *kubelet {
+	t.Helper()
+
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &kubelet{registered: make(chan string, 1)}
+	srv := grpc.NewServer()
+	v1beta1.RegisterRegistrationServer(srv, k)
+	go srv.Serve(lis) //nolint:errcheck // best effort call
+	t.Cleanup(srv.Stop)
+	return k
+}
+
+// eventually polls cond until it is true or a timeout is reached.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeRegistrations(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		kubelet bool
+		result  string
+	}{
+		{name: "success", kubelet: true, result: "success"},
+		{name: "failure", result: "failure"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			kubeletSocket := filepath.Join(dir, "kubelet.sock")
+			var k *kubelet
+			if tc.kubelet {
+				k = startKubelet(t, kubeletSocket)
+			}
+
+			name := "example.com/registrations-" + tc.name
+			dps := plugin.New(nil)
+			dps.SetKubeletSocket(kubeletSocket)
+			devicePluginServer := newServer(name, "unix://"+filepath.Join(dir, "plugin.sock"))
+			healthServer := health.NewServer()
+			grpcServer := dps.DevicePluginServer(devicePluginServer)
+			grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			errs := make(chan error, 1)
+			go func() {
+				errs <- serve(ctx, slog.New(slog.DiscardHandler), dps, grpcServer, healthServer, nil, devicePluginServer)
+			}()
+
+			registrations := metrics.Registrations.WithLabelValues(name, plugin.ReasonStartup, tc.result)
+			eventually(t, func() bool { return testutil.ToFloat64(registrations) >= 1 },
+				"registration was not recorded")
+
+			want := 0.0
+			if tc.kubelet {
+				want = 1
+				if got := <-k.registered; got != name {
+					t.Errorf("kubelet registered %q, want %q", got, name)
+				}
+			}
+			if got := testutil.ToFloat64(metrics.Registered.WithLabelValues(name)); got != want {
+				t.Errorf("registered = %v, want %v", got, want)
+			}
+
+			cancel()
+			grpcServer.Stop()
+			if err := <-errs; err != nil {
+				t.Errorf("serve() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+// TestRegisterSpans is not parallel, as it replaces the global tracer provider.
+func TestRegisterSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	dir := t.TempDir()
+	kubeletSocket := filepath.Join(dir, "kubelet.sock")
+	name, socket := "example.com/spans", "unix://"+filepath.Join(dir, "plugin.sock")
+
+	dps := plugin.New(nil)
+	dps.SetKubeletSocket(kubeletSocket)
+	healthServer := health.NewServer()
+	grpcServer := dps.DevicePluginServer(newServer(name, socket))
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+	healthServer.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	lis, cleanup, err := listener(t.Context(), slog.New(slog.DiscardHandler), socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	go grpcServer.Serve(lis) //nolint:errcheck // best effort call
+	t.Cleanup(grpcServer.Stop)
+
+	// kubelet is not running yet
+	if err := register(t.Context(), dps, name, socket, plugin.ReasonStartup); err == nil {
+		t.Fatal("register() = nil without kubelet, want error")
+	}
+	startKubelet(t, kubeletSocket)
+	if err := register(t.Context(), dps, name, socket, plugin.ReasonKubeletRestart); err != nil {
+		t.Fatal(err)
+	}
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range rec.Ended() {
+		if span.Name() == "Register" {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d Register spans, want 2", len(spans))
+	}
+	for i, want := range []struct {
+		reason string
+		code   codes.Code
+	}{
+		{reason: plugin.ReasonStartup, code: codes.Error},
+		{reason: plugin.ReasonKubeletRestart, code: codes.Unset},
+	} {
+		attrs := attribute.NewSet(spans[i].Attributes()...)
+		if got, _ := attrs.Value(tracing.ResourceKey); got.AsString() != name {
+			t.Errorf("resource of span %d = %q, want %q", i, got.AsString(), name)
+		}
+		if got, _ := attrs.Value(tracing.ReasonKey); got.AsString() != want.reason {
+			t.Errorf("reason of span %d = %q, want %q", i, got.AsString(), want.reason)
+		}
+		if got := spans[i].Status().Code; got != want.code {
+			t.Errorf("status of span %d = %v, want %v", i, got, want.code)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/podresources"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Options holds optional settings of the plugin runtime.
+type Options struct {
+	// ResyncInterval is the interval of the periodic device discovery, used as a
+	// safety net for missed filesystem events. Defaults to discovery.DefaultResyncInterval.
+	ResyncInterval time.Duration
+
+	// MetricsAddress is the address the metrics endpoint listens on, e.g. DefaultMetricsAddress.
+	// The metrics endpoint is disabled if empty.
+	MetricsAddress string
+
+	// MetricsCertFile and MetricsKeyFile enable TLS on the metrics endpoint. The certificate
+	// is reloaded whenever the files change.
+	MetricsCertFile string
+	MetricsKeyFile  string
+
+	// MetricsAuth requires requests to the metrics endpoint to carry a bearer token, which
+	// is authenticated and authorized by the Kubernetes API server.
+	MetricsAuth bool
+
+	// PodResourcesInterval is the interval of reconciling device owners from the kubelet
+	// PodResources API. Owners are served on /allocations of the metrics endpoint.
+	// The reconciliation is disabled if zero.
+	PodResourcesInterval time.Duration
+
+	// PodResourcesSocket is the path of the kubelet PodResources API socket.
+	// Defaults to podresources.DefaultSocket.
+	PodResourcesSocket string
+
+	// TracingEndpoint is the OTLP/gRPC endpoint spans are exported to, e.g.
+	// "http://otel-collector:4317". Tracing is disabled if empty.
+	TracingEndpoint string
+
+	// NodeName is the name of the node the plugin runs on. If set, Kubernetes Events about
+	// registration and device changes are emitted on the Node object.
+	NodeName string
+
+	// FeaturesDir is the directory of Node Feature Discovery local feature files, where
+	// features of the devices are written to. Feature files are disabled if empty.
+	FeaturesDir string
+
+	// LogLevel, if set, can be changed at runtime on /loglevel. It is served on the metrics
+	// endpoint only with MetricsAuth, otherwise anyone reaching the endpoint could change it.
+	LogLevel *slog.LevelVar
+
+	// LogLevelEndpoint enables a separate endpoint serving LogLevel on LogLevelAddress,
+	// e.g. DefaultLogLevelAddress, which is only reachable from within the pod.
+	LogLevelEndpoint bool
+	LogLevelAddress  string
+
+	// KubeClient is the client of the Kubernetes API server. If nil, an in-cluster client
+	// is created when needed.
+	KubeClient kubernetes.Interface
+}
+
+// AddFlags registers flags of the options shared by all plugin commands.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.ResyncInterval, "resync-interval", discovery.DefaultResyncInterval,
+		"Set interval of periodic device discovery, complementing filesystem events")
+	fs.StringVar(&o.MetricsAddress, "metrics-bind-address", DefaultMetricsAddress,
+		"Set address the metrics endpoint listens on (empty disables the endpoint)")
+	fs.StringVar(&o.MetricsCertFile, "metrics-cert-file", "",
+		"Set path to the TLS certificate of the metrics endpoint, enables TLS")
+	fs.StringVar(&o.MetricsKeyFile, "metrics-key-file", "",
+		"Set path to the TLS key of the metrics endpoint, enables TLS")
+	fs.BoolVar(&o.MetricsAuth, "metrics-auth", false,
+		"Require Kubernetes authentication and authorization of requests to the metrics endpoint")
+	fs.BoolVar(&o.LogLevelEndpoint, "log-level-endpoint", false,
+		"Serve /loglevel, changing the log level at runtime, on --log-level-bind-address")
+	fs.StringVar(&o.LogLevelAddress, "log-level-bind-address", DefaultLogLevelAddress,
+		"Set address the log level endpoint listens on")
+	fs.StringVar(&o.TracingEndpoint, "tracing-endpoint", "",
+		"Set OTLP/gRPC endpoint traces are exported to, e.g. http://otel-collector:4317 (empty disables tracing)")
+	fs.StringVar(&o.NodeName, "node-name", os.Getenv("NODE_NAME"),
+		"Set name of the node events are emitted on, defaults to $NODE_NAME (empty disables events)")
+	fs.StringVar(&o.FeaturesDir, "features-dir", "",
+		"Set directory of NFD local feature files, e.g. "+features.DefaultDir+" (empty disables feature labels)")
+	fs.DurationVar(&o.PodResourcesInterval, "pod-resources-interval", 0,
+		"Set interval of tracking device owners via kubelet PodResources API (0 disables)")
+	fs.StringVar(&o.PodResourcesSocket, "pod-resources-socket", podresources.DefaultSocket,
+		"Set path to the kubelet PodResources API socket")
+}
+
+// Validate checks that the options are consistent.
+func (o *Options) Validate() error {
+	if (o.MetricsCertFile == "") != (o.MetricsKeyFile == "") {
+		return errors.New("--metrics-cert-file and --metrics-key-file must be set together")
+	}
+	if o.MetricsAuth && o.MetricsCertFile == "" {
+		// bearer tokens must not be sent in plain text
+		return errors.New("--metrics-auth requires TLS, set --metrics-cert-file and --metrics-key-file")
+	}
+	if o.LogLevelEndpoint && o.LogLevelAddress == "" {
+		return errors.New("--log-level-endpoint requires --log-level-bind-address")
+	}
+	return nil
+}
+
+func (o *Options) kubeClient() (kubernetes.Interface, error) {
+	if o.KubeClient != nil {
+		return o.KubeClient, nil
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		if errors.Is(err, rest.ErrNotInCluster) {
+			return nil, fmt.Errorf("kubernetes client is required, but plugin is not running in cluster: %w", err)
+		}
+		return nil, fmt.Errorf("failed to load in-cluster config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	o.KubeClient = client
+	return client, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package entrypoint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "tls",
+			opts: Options{MetricsCertFile: "tls.crt", MetricsKeyFile: "tls.key"},
+		},
+		{
+			name: "auth with tls",
+			opts: Options{MetricsCertFile: "tls.crt", MetricsKeyFile: "tls.key", MetricsAuth: true},
+		},
+		{
+			name: "cert without key",
+			opts: Options{MetricsCertFile: "tls.crt"},
+			err:  "must be set together",
+		},
+		{
+			name: "key without cert",
+			opts: Options{MetricsKeyFile: "tls.key"},
+			err:  "must be set together",
+		},
+		{
+			name: "log level endpoint",
+			opts: Options{LogLevelEndpoint: true, LogLevelAddress: DefaultLogLevelAddress},
+		},
+		{
+			name: "log level endpoint without address",
+			opts: Options{LogLevelEndpoint: true},
+			err:  "requires --log-level-bind-address",
+		},
+		{
+			name: "auth without tls",
+			opts: Options{MetricsAuth: true},
+			err:  "requires TLS",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.opts.Validate()
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
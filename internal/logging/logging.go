@@ -0,0 +1,138 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// Supported log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// maxLevelSize limits the size of level change requests.
+const maxLevelSize = 64
+
+// Options holds the logging settings shared by all plugin commands.
+type Options struct {
+	Level  string
+	Format string
+}
+
+// AddFlags registers the logging flags.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Level, "log-level", "info", "Set log level (debug, info, warn, error)")
+	fs.StringVar(&o.Format, "log-format", FormatText, "Set log format (text, json)")
+}
+
+// New creates a logger writing to w. The returned level can be changed at runtime,
+// e.g. with LevelHandler.
+func (o Options) New(w io.Writer) (*slog.Logger, *slog.LevelVar, error) {
+	level := &slog.LevelVar{}
+	if o.Level != "" {
+		l, err := ParseLevel(o.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+		level.Set(l)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch o.Format {
+	case FormatText, "":
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("unsupported log format %q, expected %s or %s", o.Format, FormatText, FormatJSON)
+	}
+
+	return slog.New(handler), level, nil
+}
+
+// ParseLevel parses a log level, one of debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return level, fmt.Errorf("invalid log level %q: %w", s, err)
+	}
+	return level, nil
+}
+
+// LevelHandler serves the current log level on GET, and changes it on PUT or POST,
+// with the new level given in the request body or in the "level" query parameter:
+//
+//	curl -X PUT -d debug http://localhost:8081/loglevel
+type LevelHandler struct {
+	log   *slog.Logger
+	level *slog.LevelVar
+}
+
+func NewLevelHandler(level *slog.LevelVar, log *slog.Logger) *LevelHandler {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &LevelHandler{
+		log:   log,
+		level: level,
+	}
+}
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+
+	case http.MethodPut, http.MethodPost:
+		value := req.URL.Query().Get("level")
+		if value == "" {
+			body, err := io.ReadAll(io.LimitReader(req.Body, maxLevelSize))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			value = string(body)
+		}
+
+		level, err := ParseLevel(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if old := h.level.Level(); old != level {
+			h.level.Set(level)
+			h.log.Info("Log level changed", "from", old.String(), "to", level.String())
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.ToLower(h.level.Level().String())) //nolint:errcheck // best effort call
+}
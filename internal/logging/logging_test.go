@@ -0,0 +1,155 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  slog.Level
+		err   string
+	}{
+		{name: "debug", value: "debug", want: slog.LevelDebug},
+		{name: "info", value: "info", want: slog.LevelInfo},
+		{name: "upper case", value: "WARN", want: slog.LevelWarn},
+		{name: "surrounding space", value: " error\n", want: slog.LevelError},
+		{name: "empty", value: "", err: "invalid log level"},
+		{name: "unknown", value: "verbose", err: "invalid log level"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseLevel(tc.value)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			case tc.err == "" && got != tc.want:
+				t.Errorf("ParseLevel(%q) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOptionsNew(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		opts Options
+		json bool
+		err  string
+	}{
+		{name: "defaults", opts: Options{}},
+		{name: "text", opts: Options{Level: "debug", Format: FormatText}},
+		{name: "json", opts: Options{Level: "debug", Format: FormatJSON}, json: true},
+		{name: "invalid format", opts: Options{Format: "xml"}, err: "unsupported log format"},
+		{name: "invalid level", opts: Options{Level: "verbose"}, err: "invalid log level"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			log, level, err := tc.opts.New(&buf)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			case tc.err != "":
+				return
+			}
+
+			log.Debug("Debug message")
+			log.Info("Info message", "key", "value")
+
+			// the level defaults to info
+			debug := tc.opts.Level == "debug"
+			if got := strings.Contains(buf.String(), "Debug message"); got != debug {
+				t.Errorf("debug message logged: %v, want %v", got, debug)
+			}
+			level.Set(slog.LevelWarn)
+			log.Info("Suppressed message")
+			if strings.Contains(buf.String(), "Suppressed message") {
+				t.Error("info message logged after raising the level")
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			last := lines[len(lines)-1]
+			var entry map[string]any
+			isJSON := json.Unmarshal([]byte(last), &entry) == nil
+			if isJSON != tc.json {
+				t.Errorf("line %q is JSON: %v, want %v", last, isJSON, tc.json)
+			}
+			if tc.json && entry["key"] != "value" {
+				t.Errorf("key = %v, want value", entry["key"])
+			}
+			if !tc.json && !strings.Contains(last, "key=value") {
+				t.Errorf("line %q does not contain key=value", last)
+			}
+		})
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	t.Parallel()
+
+	level := &slog.LevelVar{}
+	h := NewLevelHandler(level, nil)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+		want   string
+	}{
+		{name: "get", method: http.MethodGet, target: "/loglevel", code: http.StatusOK, want: "info"},
+		{name: "put body", method: http.MethodPut, target: "/loglevel", body: "debug", code: http.StatusOK, want: "debug"},
+		{name: "post query", method: http.MethodPost, target: "/loglevel?level=warn", code: http.StatusOK, want: "warn"},
+		{name: "bad level", method: http.MethodPut, target: "/loglevel", body: "verbose", code: http.StatusBadRequest},
+		{name: "unchanged", method: http.MethodGet, target: "/loglevel", code: http.StatusOK, want: "warn"},
+		{name: "bad method", method: http.MethodDelete, target: "/loglevel", code: http.StatusMethodNotAllowed},
+	} {
+		// steps depend on each other, so they are not run as parallel subtests
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+
+		if rec.Code != tc.code {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.code)
+		}
+		if tc.want != "" && strings.TrimSpace(rec.Body.String()) != tc.want {
+			t.Errorf("%s: body = %q, want %q", tc.name, rec.Body.String(), tc.want)
+		}
+	}
+
+	if got := level.Level(); got != slog.LevelWarn {
+		t.Errorf("level = %v, want %v", got, slog.LevelWarn)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cdi maintains Container Device Interface spec files for the devices
+// advertised by the plugins, see https://github.com/cncf-tags/container-device-interface.
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	// DefaultSpecDir is the directory container runtimes load dynamic CDI specs from.
+	DefaultSpecDir = "/var/run/cdi"
+
+	// Version is the CDI spec version of the generated files.
+	Version = "0.6.0"
+)
+
+// Mode selects how allocated devices are passed to the container runtime.
+type Mode string
+
+const (
+	// ModeDeviceSpec injects devices as DeviceSpec host paths.
+	ModeDeviceSpec Mode = "device-spec"
+	// ModeCDI injects devices as fully qualified CDI device names.
+	ModeCDI Mode = "cdi"
+	// ModeBoth returns both DeviceSpecs and CDI device names.
+	ModeBoth Mode = "both"
+)
+
+// ParseMode parses the value of the --device-mode flag.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeDeviceSpec, ModeCDI, ModeBoth:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown device mode %q, expected one of: %s, %s, %s",
+			s, ModeDeviceSpec, ModeCDI, ModeBoth)
+	}
+}
+
+// DeviceSpec reports whether DeviceSpecs should be returned from Allocate.
+func (m Mode) DeviceSpec() bool {
+	return m == "" || m == ModeDeviceSpec || m == ModeBoth
+}
+
+// CDI reports whether CDI devices should be returned from Allocate.
+func (m Mode) CDI() bool {
+	return m == ModeCDI || m == ModeBoth
+}
+
+// Config holds the CDI settings shared by all servers.
+type Config struct {
+	Mode    Mode
+	SpecDir string
+}
+
+// Spec is the subset of the CDI specification used by the plugins.
+type Spec struct {
+	Version string   `json:"cdiVersion"`
+	Kind    string   `json:"kind"`
+	Devices []Device `json:"devices"`
+}
+
+type Device struct {
+	Name           string            `json:"name"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+	ContainerEdits ContainerEdits    `json:"containerEdits"`
+}
+
+type ContainerEdits struct {
+	Env         []string      `json:"env,omitempty"`
+	DeviceNodes []*DeviceNode `json:"deviceNodes,omitempty"`
+	Mounts      []*Mount      `json:"mounts,omitempty"`
+}
+
+type DeviceNode struct {
+	Path        string `json:"path"`
+	HostPath    string `json:"hostPath,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+type Mount struct {
+	HostPath      string   `json:"hostPath"`
+	ContainerPath string   `json:"containerPath"`
+	Type          string   `json:"type,omitempty"`
+	Options       []string `json:"options,omitempty"`
+}
+
+// QualifiedName returns the fully qualified CDI name of a device, e.g.
+// devices.anza-labs.dev/kvm=kvm0.
+func QualifiedName(kind, device string) string {
+	return kind + "=" + device
+}
+
+// Devices returns the CDI devices for the given device IDs, to be used in a
+// ContainerAllocateResponse.
+func Devices(kind string, ids []string) []*v1beta1.CDIDevice {
+	devs := make([]*v1beta1.CDIDevice, 0, len(ids))
+	for _, id := range ids {
+		devs = append(devs, &v1beta1.CDIDevice{Name: QualifiedName(kind, id)})
+	}
+	return devs
+}
+
+// Sync writes the spec of the kind into the spec directory. If the spec has no devices,
+// the spec file is removed instead, as runtimes reject specs without devices.
+func (c Config) Sync(spec *Spec) error {
+	if !c.Mode.CDI() {
+		return nil
+	}
+
+	if spec.Version == "" {
+		spec.Version = Version
+	}
+
+	file := c.specFile(spec.Kind)
+
+	if len(spec.Devices) == 0 {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove CDI spec: %w", err)
+		}
+		return nil
+	}
+
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CDI spec: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("failed to create CDI spec directory: %w", err)
+	}
+
+	// Write to a temporary file first, so that runtimes never observe a partial spec
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-"+filepath.Base(file))
+	if err != nil {
+		return fmt.Errorf("failed to create CDI spec: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // best effort call
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() //nolint:errcheck // best effort call
+		return fmt.Errorf("failed to write CDI spec: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close() //nolint:errcheck // best effort call
+		return fmt.Errorf("failed to write CDI spec: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write CDI spec: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to write CDI spec: %w", err)
+	}
+
+	return nil
+}
+
+// specFile returns the path of the spec file, named <vendor>-<class>.yaml as recommended
+// by the CDI specification.
+func (c Config) specFile(kind string) string {
+	dir := c.SpecDir
+	if dir == "" {
+		dir = DefaultSpecDir
+	}
+	return filepath.Join(dir, strings.ReplaceAll(kind, "/", "-")+".yaml")
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdi
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name       string
+		value      string
+		want       Mode
+		deviceSpec bool
+		cdi        bool
+		err        string
+	}{
+		{name: "device-spec", value: "device-spec", want: ModeDeviceSpec, deviceSpec: true},
+		{name: "cdi", value: "cdi", want: ModeCDI, cdi: true},
+		{name: "both", value: "both", want: ModeBoth, deviceSpec: true, cdi: true},
+		{name: "empty", value: "", err: "unknown device mode"},
+		{name: "invalid", value: "CDI", err: "unknown device mode"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseMode(tc.value)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			case tc.err != "":
+				return
+			}
+			if got != tc.want {
+				t.Errorf("ParseMode(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+			if got.DeviceSpec() != tc.deviceSpec {
+				t.Errorf("DeviceSpec() = %v, want %v", got.DeviceSpec(), tc.deviceSpec)
+			}
+			if got.CDI() != tc.cdi {
+				t.Errorf("CDI() = %v, want %v", got.CDI(), tc.cdi)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c := Config{Mode: ModeCDI, SpecDir: filepath.Join(dir, "cdi")}
+	file := filepath.Join(c.SpecDir, "example.com-test.yaml")
+
+	spec := &Spec{
+		Kind: "example.com/test",
+		Devices: []Device{
+			{
+				Name: "test0",
+				ContainerEdits: ContainerEdits{
+					DeviceNodes: []*DeviceNode{{Path: "/dev/test", Permissions: "rw"}},
+				},
+			},
+		},
+	}
+	if err := c.Sync(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Spec
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != Version || got.Kind != spec.Kind {
+		t.Errorf("spec version and kind = %q, %q, want %q, %q", got.Version, got.Kind, Version, spec.Kind)
+	}
+	if len(got.Devices) != 1 || got.Devices[0].Name != "test0" ||
+		len(got.Devices[0].ContainerEdits.DeviceNodes) != 1 ||
+		got.Devices[0].ContainerEdits.DeviceNodes[0].Path != "/dev/test" {
+		t.Errorf("spec devices = %+v, want test0 with /dev/test", got.Devices)
+	}
+
+	// the spec is replaced without leaving temporary files behind
+	spec.Devices = append(spec.Devices, Device{Name: "test1"})
+	if err := c.Sync(spec); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(c.SpecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(file) {
+		t.Errorf("spec directory contains %v, want only %s", entries, filepath.Base(file))
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("spec file mode = %v (%v), want 0644", info, err)
+	}
+
+	// runtimes reject specs without devices
+	if err := c.Sync(&Spec{Kind: spec.Kind}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("spec file was not removed: %v", err)
+	}
+	if err := c.Sync(&Spec{Kind: spec.Kind}); err != nil {
+		t.Errorf("removing a missing spec failed: %v", err)
+	}
+}
+
+func TestSyncDeviceSpec(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "cdi")
+	c := Config{Mode: ModeDeviceSpec, SpecDir: dir}
+	if err := c.Sync(&Spec{Kind: "example.com/test", Devices: []Device{{Name: "test0"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("spec directory created without CDI mode: %v", err)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	t.Parallel()
+
+	devs := Devices("example.com/test", []string{"test0", "test1"})
+	if len(devs) != 2 {
+		t.Fatalf("Devices() = %v, want 2 devices", devs)
+	}
+	for i, want := range []string{"example.com/test=test0", "example.com/test=test1"} {
+		if devs[i].Name != want {
+			t.Errorf("device %d = %q, want %q", i, devs[i].Name, want)
+		}
+	}
+}
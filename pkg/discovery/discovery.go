@@ -17,26 +17,99 @@ package discovery
 import (
 	"context"
 	"errors"
+	"io/fs"
 	"log/slog"
+	"path/filepath"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
 )
 
+// DefaultResyncInterval is the interval of the periodic discovery used as a safety net
+// for missed filesystem events.
+const DefaultResyncInterval = 30 * time.Second
+
+// watchedOps are the filesystem operations that may change the presence or the
+// permissions of a device node.
+const watchedOps = fsnotify.Create | fsnotify.Remove | fsnotify.Rename | fsnotify.Chmod
+
 type DiscoverUpdater interface {
 	Discover() error
 	Update()
 }
 
-func Discovery(ctx context.Context, log *slog.Logger, server DiscoverUpdater) error {
-	t := time.NewTicker(time.Second)
+// Watcher is implemented by servers that know the paths of their device nodes.
+// Discovery watches those paths (and their parent directories) and runs discovery
+// as soon as they change, instead of waiting for the next resync.
+type Watcher interface {
+	WatchPaths() []string
+}
+
+func Discovery(ctx context.Context, log *slog.Logger, server DiscoverUpdater, resync time.Duration) error {
+	if resync <= 0 {
+		resync = DefaultResyncInterval
+	}
+
+	t := time.NewTicker(resync)
 	defer t.Stop()
 
+	var (
+		fsw    *fsnotify.Watcher
+		paths  []string
+		events chan fsnotify.Event
+		errs   chan error
+	)
+
+	if w, ok := server.(Watcher); ok {
+		paths = w.WatchPaths()
+	}
+
+	if len(paths) > 0 {
+		var err error
+		fsw, err = fsnotify.NewWatcher()
+		if err != nil {
+			log.Error("Failed to create filesystem watcher, falling back to periodic discovery", "error", err)
+		} else {
+			defer fsw.Close() //nolint:errcheck // best effort call
+
+			watch(log, fsw, paths)
+			events, errs = fsw.Events, fsw.Errors
+		}
+	}
+
 	for {
 		select {
-		case <-t.C:
-			if err := server.Discover(); err != nil {
-				log.Error("Discovery failed", "error", err)
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if !ev.Has(watchedOps) || !relevant(ev.Name, paths) {
+				continue
+			}
+
+			log.Debug("Device event", "path", ev.Name, "op", ev.Op.String())
+			if ev.Has(fsnotify.Create) {
+				// a parent directory (e.g. /dev/net) might have just been created
+				watch(log, fsw, paths)
+			}
+			discover(ctx, log, server, "event")
+
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
 			}
-			server.Update()
+			log.Error("Filesystem watcher failed", "error", err)
+
+		case <-t.C:
+			discover(ctx, log, server, "resync")
+
 		case <-ctx.Done():
 			err := ctx.Err()
 			if errors.Is(err, context.Canceled) {
@@ -46,3 +119,70 @@ func Discovery(ctx context.Context, log *slog.Logger, server DiscoverUpdater) er
 		}
 	}
 }
+
+// discover runs a single discovery cycle, traced as a span with the trigger of the cycle.
+func discover(ctx context.Context, log *slog.Logger, server DiscoverUpdater, trigger string) {
+	var resource string
+	if named, ok := server.(interface{ Name() string }); ok {
+		resource = named.Name()
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "Discovery", trace.WithAttributes(
+		tracing.ResourceKey.String(resource),
+		attribute.String("device_plugin.discovery.trigger", trigger),
+	))
+
+	err := server.Discover()
+	if err != nil {
+		log.Error("Discovery failed", "error", err)
+		if resource != "" {
+			metrics.DiscoveryErrors.WithLabelValues(resource).Inc()
+		}
+	}
+	server.Update()
+
+	tracing.End(span, err)
+}
+
+// watch adds watches on all existing parent directories of paths. Directories are
+// watched instead of the device nodes themselves, so that creation of a missing node
+// is observed as well.
+func watch(log *slog.Logger, fsw *fsnotify.Watcher, paths []string) {
+	for _, dir := range parents(paths) {
+		if err := fsw.Add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error("Failed to watch directory", "path", dir, "error", err)
+		}
+	}
+}
+
+// parents returns all parent directories of paths, excluding the filesystem root.
+func parents(paths []string) []string {
+	seen := map[string]struct{}{}
+	dirs := []string{}
+
+	for _, p := range paths {
+		for dir := filepath.Dir(filepath.Clean(p)); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if _, ok := seen[dir]; ok {
+				continue
+			}
+			seen[dir] = struct{}{}
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// relevant reports whether name matches one of paths or one of their parent directories.
+// Paths may be glob patterns, as accepted by filepath.Match.
+func relevant(name string, paths []string) bool {
+	name = filepath.Clean(name)
+	for _, p := range paths {
+		for p = filepath.Clean(p); p != filepath.Dir(p); p = filepath.Dir(p) {
+			if ok, _ := filepath.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
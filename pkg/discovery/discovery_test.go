@@ -0,0 +1,265 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
+)
+
+func TestRelevant(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		path  string
+		paths []string
+		want  bool
+	}{
+		{name: "exact match", path: "/dev/kvm", paths: []string{"/dev/kvm"}, want: true},
+		{name: "other node", path: "/dev/fuse", paths: []string{"/dev/kvm"}, want: false},
+		{name: "parent directory", path: "/dev/net", paths: []string{"/dev/net/tun"}, want: true},
+		{name: "sibling directory", path: "/dev/snd", paths: []string{"/dev/net/tun"}, want: false},
+		{name: "glob match", path: "/dev/loop7", paths: []string{"/dev/loop[0-9]*"}, want: true},
+		{name: "glob mismatch", path: "/dev/loop-control", paths: []string{"/dev/loop[0-9]*"}, want: false},
+		{name: "glob in directory", path: "/dev/vfio/42", paths: []string{"/dev/vfio/*"}, want: true},
+		{name: "parent of glob", path: "/dev/serial", paths: []string{"/dev/serial/by-id/*"}, want: true},
+		{name: "unclean path", path: "/dev//net/tun", paths: []string{"/dev/net/tun"}, want: true},
+		{name: "any of paths", path: "/dev/vhost-net", paths: []string{"/dev/kvm", "/dev/vhost-net"}, want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := relevant(tc.path, tc.paths); got != tc.want {
+				t.Errorf("relevant(%q, %q) = %v, want %v", tc.path, tc.paths, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParents(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{name: "single path", paths: []string{"/dev/net/tun"}, want: []string{"/dev/net", "/dev"}},
+		{name: "shared parents", paths: []string{"/dev/net/tun", "/dev/kvm"}, want: []string{"/dev/net", "/dev"}},
+		{name: "glob", paths: []string{"/dev/serial/by-id/*"}, want: []string{"/dev/serial/by-id", "/dev/serial", "/dev"}},
+		{name: "root", paths: []string{"/kvm"}, want: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parents(tc.paths); !slices.Equal(got, tc.want) {
+				t.Errorf("parents(%q) = %q, want %q", tc.paths, got, tc.want)
+			}
+		})
+	}
+}
+
+// counter counts discovery cycles.
+type counter struct {
+	discovered atomic.Int32
+}
+
+func (c *counter) Discover() error {
+	c.discovered.Add(1)
+	return nil
+}
+
+func (c *counter) Update() {}
+
+type watchingCounter struct {
+	counter
+	paths []string
+}
+
+func (c *watchingCounter) WatchPaths() []string {
+	return c.paths
+}
+
+// run starts discovery of the server, which only resyncs once an hour, and waits until
+// the directory is watched.
+func run(t *testing.T, server *watchingCounter) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- Discovery(ctx, slog.New(slog.DiscardHandler), server, time.Hour)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Discovery() = %v", err)
+		}
+	})
+
+	// watches are added right after discovery starts
+	time.Sleep(100 * time.Millisecond)
+}
+
+// wait waits until the server ran more than n discovery cycles.
+func wait(t *testing.T, server *watchingCounter, n int32) int32 {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := server.discovered.Load(); got > n {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no discovery after %d cycles", n)
+	return 0
+}
+
+func TestDiscoveryNestedDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	server := &watchingCounter{paths: []string{filepath.Join(dir, "net", "tun")}}
+	run(t, server)
+
+	n := server.discovered.Load()
+	if err := os.Mkdir(filepath.Join(dir, "net"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	n = wait(t, server, n)
+
+	// the created directory is watched, so the node created in it is observed
+	time.Sleep(100 * time.Millisecond)
+	n = server.discovered.Load()
+	if err := os.WriteFile(filepath.Join(dir, "net", "tun"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, server, n)
+}
+
+func TestDiscoveryRecreatedDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "vfio")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	server := &watchingCounter{paths: []string{filepath.Join(sub, "*")}}
+	run(t, server)
+
+	n := server.discovered.Load()
+	if err := os.Remove(sub); err != nil {
+		t.Fatal(err)
+	}
+	n = wait(t, server, n)
+
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, server, n)
+
+	time.Sleep(100 * time.Millisecond)
+	n = server.discovered.Load()
+	if err := os.WriteFile(filepath.Join(sub, "42"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, server, n)
+}
+
+func TestDiscoveryIgnoresUnrelatedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	server := &watchingCounter{paths: []string{filepath.Join(dir, "loop[0-9]*")}}
+	run(t, server)
+
+	n := server.discovered.Load()
+	if err := os.WriteFile(filepath.Join(dir, "loop-control"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "loop3"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, server, n)
+
+	time.Sleep(100 * time.Millisecond)
+	if got := server.discovered.Load(); got != n+1 {
+		t.Errorf("discovered %d times, want once for loop3 only", got-n)
+	}
+}
+
+// failing fails every discovery cycle.
+type failing struct{}
+
+func (failing) Name() string    { return "example.com/failing" }
+func (failing) Discover() error { return errors.New("failed") }
+func (failing) Update()         {}
+
+// TestDiscoverSpans is not parallel, as it replaces the global tracer provider.
+func TestDiscoverSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	log := slog.New(slog.DiscardHandler)
+	discover(t.Context(), log, &counter{}, "resync")
+	discover(t.Context(), log, failing{}, "event")
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	for i, want := range []struct {
+		resource, trigger string
+		code              codes.Code
+	}{
+		{resource: "", trigger: "resync", code: codes.Unset},
+		{resource: "example.com/failing", trigger: "event", code: codes.Error},
+	} {
+		span := spans[i]
+		if span.Name() != "Discovery" {
+			t.Errorf("span %d = %q, want Discovery", i, span.Name())
+		}
+		attrs := attribute.NewSet(span.Attributes()...)
+		if got, _ := attrs.Value(tracing.ResourceKey); got.AsString() != want.resource {
+			t.Errorf("resource of span %d = %q, want %q", i, got.AsString(), want.resource)
+		}
+		if got, _ := attrs.Value("device_plugin.discovery.trigger"); got.AsString() != want.trigger {
+			t.Errorf("trigger of span %d = %q, want %q", i, got.AsString(), want.trigger)
+		}
+		if got := span.Status().Code; got != want.code {
+			t.Errorf("status of span %d = %v, want %v", i, got, want.code)
+		}
+	}
+}
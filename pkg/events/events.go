@@ -0,0 +1,112 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the events emitted by the plugins.
+const (
+	ReasonRegistered         = "DevicePluginRegistered"
+	ReasonRegistrationFailed = "DevicePluginRegistrationFailed"
+	ReasonDevicesAppeared    = "DevicesAppeared"
+	ReasonDevicesDisappeared = "DevicesDisappeared"
+	ReasonDevicesHealthy     = "DevicesHealthy"
+	ReasonDevicesUnhealthy   = "DevicesUnhealthy"
+)
+
+const (
+	// component is the source of the events.
+	component = "kubelet-device-plugins"
+
+	// eventsQPS and eventsBurst limit the rate of events sent per object, in addition to
+	// the aggregation of similar events done by the broadcaster.
+	eventsQPS   = 1.0 / 60
+	eventsBurst = 25
+)
+
+// Recorder emits Kubernetes Events on the Node object the plugins run on. Similar events
+// are aggregated and rate limited. A nil Recorder drops all events, so that callers do
+// not need to check whether events are enabled.
+type Recorder struct {
+	log         *slog.Logger
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	node        *corev1.ObjectReference
+}
+
+// New creates a Recorder emitting events on the node using client.
+func New(client kubernetes.Interface, node string, log *slog.Logger) *Recorder {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	broadcaster := record.NewBroadcaster(record.WithCorrelatorOptions(record.CorrelatorOptions{
+		QPS:       eventsQPS,
+		BurstSize: eventsBurst,
+	}))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: client.CoreV1().Events(""),
+	})
+
+	return &Recorder{
+		log:         log,
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component, Host: node}),
+		// kubelet uses the node name as UID of node events as well
+		node: &corev1.ObjectReference{
+			Kind:       "Node",
+			APIVersion: "v1",
+			Name:       node,
+			UID:        types.UID(node),
+		},
+	}
+}
+
+// Normal emits an event of type Normal.
+func (r *Recorder) Normal(reason, format string, args ...any) {
+	r.event(corev1.EventTypeNormal, reason, format, args...)
+}
+
+// Warning emits an event of type Warning.
+func (r *Recorder) Warning(reason, format string, args ...any) {
+	r.event(corev1.EventTypeWarning, reason, format, args...)
+}
+
+func (r *Recorder) event(eventtype, reason, format string, args ...any) {
+	if r == nil {
+		return
+	}
+
+	r.log.Debug("Emitting event", "type", eventtype, "reason", reason, "message", fmt.Sprintf(format, args...))
+	r.recorder.Eventf(r.node, eventtype, reason, format, args...)
+}
+
+// Shutdown stops sending events. Events still queued are dropped.
+func (r *Recorder) Shutdown() {
+	if r == nil {
+		return
+	}
+	r.broadcaster.Shutdown()
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// waitForEvents polls the events of the fake client until done returns true.
+func waitForEvents(t *testing.T, client *fake.Clientset, done func([]corev1.Event) bool) []corev1.Event {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		list, err := client.CoreV1().Events("").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(list.Items) {
+			return list.Items
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for events, got %v", list.Items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	rec := New(client, "node-1", nil)
+	t.Cleanup(rec.Shutdown)
+
+	rec.Normal(ReasonRegistered, "Registered %s with kubelet (%s)", "example.com/test", "startup")
+	rec.Warning(ReasonRegistrationFailed, "Failed to register %s with kubelet (%s): %v", "example.com/test",
+		"kubelet restart", "connection refused")
+
+	got := waitForEvents(t, client, func(events []corev1.Event) bool { return len(events) == 2 })
+
+	want := map[string]struct{ eventtype, message string }{
+		ReasonRegistered: {
+			corev1.EventTypeNormal, "Registered example.com/test with kubelet (startup)",
+		},
+		ReasonRegistrationFailed: {
+			corev1.EventTypeWarning,
+			"Failed to register example.com/test with kubelet (kubelet restart): connection refused",
+		},
+	}
+	for _, event := range got {
+		w, ok := want[event.Reason]
+		if !ok {
+			t.Errorf("unexpected event %q", event.Reason)
+			continue
+		}
+		if event.Type != w.eventtype || event.Message != w.message {
+			t.Errorf("event %q = %s %q, want %s %q", event.Reason, event.Type, event.Message, w.eventtype, w.message)
+		}
+
+		obj := event.InvolvedObject
+		if obj.Kind != "Node" || obj.Name != "node-1" || obj.UID != "node-1" {
+			t.Errorf("event %q is emitted on %s %q (uid %q), want Node %q", event.Reason, obj.Kind, obj.Name, obj.UID,
+				"node-1")
+		}
+		if event.Source.Component != component || event.Source.Host != "node-1" {
+			t.Errorf("event %q has source %+v", event.Reason, event.Source)
+		}
+	}
+}
+
+func TestRecorderAggregatesDuplicates(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	rec := New(client, "node-1", nil)
+	t.Cleanup(rec.Shutdown)
+
+	for range 3 {
+		rec.Warning(ReasonDevicesUnhealthy, "Devices of %s are unhealthy: %s", "example.com/test", "a")
+	}
+
+	// duplicates only increase the count of the first event
+	waitForEvents(t, client, func(events []corev1.Event) bool {
+		return len(events) == 1 && events[0].Count == 3
+	})
+}
+
+func TestNilRecorder(t *testing.T) {
+	t.Parallel()
+
+	var rec *Recorder
+	rec.Normal(ReasonRegistered, "ignored")
+	rec.Warning(ReasonRegistrationFailed, "ignored")
+	rec.Shutdown()
+}
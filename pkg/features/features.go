@@ -0,0 +1,139 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultDir is the directory of local feature files read by Node Feature Discovery.
+	DefaultDir = "/etc/kubernetes/node-feature-discovery/features.d"
+
+	// Prefix is the label namespace of all features. It has to be allowed in NFD with
+	// -extra-label-ns, as labels outside of the NFD namespaces are denied by default.
+	Prefix = "devices.anza-labs.dev/"
+)
+
+// Source is implemented by servers that probe features of their devices. Feature names
+// are returned without Prefix.
+type Source interface {
+	Features() map[string]string
+}
+
+// File is a local feature file of Node Feature Discovery, containing one label per line:
+//
+//	devices.anza-labs.dev/kvm.vendor=intel
+//
+// A nil File ignores all writes, so that callers do not need to check whether
+// feature labels are enabled.
+type File struct {
+	path string
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+// NewFile creates the feature file of the resource in dir. The file is named after
+// the resource, with "/" replaced by "-".
+func NewFile(dir, resource string) *File {
+	if dir == "" {
+		dir = DefaultDir
+	}
+
+	return &File{
+		path: filepath.Join(dir, strings.ReplaceAll(resource, "/", "-")),
+	}
+}
+
+// Path returns the path of the feature file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Write replaces the features in the file, if they changed since the last write.
+// If there are no features, the file is removed instead.
+func (f *File) Write(features map[string]string) error {
+	if f == nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.last != nil && maps.Equal(f.last, features) {
+		return nil
+	}
+
+	if len(features) == 0 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove feature file: %w", err)
+		}
+		f.last = map[string]string{}
+		return nil
+	}
+
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(features)) {
+		fmt.Fprintf(&b, "%s%s=%s\n", Prefix, name, features[name])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create feature directory: %w", err)
+	}
+
+	// Write to a temporary file first, so that NFD never observes a partial file. The
+	// temporary file is hidden, as NFD reads all files in the directory.
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".tmp-"+filepath.Base(f.path))
+	if err != nil {
+		return fmt.Errorf("failed to create feature file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // best effort call
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close() //nolint:errcheck // best effort call
+		return fmt.Errorf("failed to write feature file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close() //nolint:errcheck // best effort call
+		return fmt.Errorf("failed to write feature file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write feature file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write feature file: %w", err)
+	}
+
+	f.last = maps.Clone(features)
+	return nil
+}
+
+// Bool formats a boolean feature value.
+func Bool(v bool) string {
+	if v {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package features
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWrite(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "features.d")
+	f := NewFile(dir, "example.com/test")
+	if want := filepath.Join(dir, "example.com-test"); f.Path() != want {
+		t.Errorf("Path() = %q, want %q", f.Path(), want)
+	}
+
+	if err := f.Write(map[string]string{"b": "2", "a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.Path(), "devices.anza-labs.dev/a=1\ndevices.anza-labs.dev/b=2\n")
+
+	// the file is replaced without leaving temporary files behind
+	if err := f.Write(map[string]string{"a": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.Path(), "devices.anza-labs.dev/a=3\n")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("feature directory contains %v, want only %s", entries, filepath.Base(f.Path()))
+	}
+
+	// unchanged features are not written again
+	if err := os.WriteFile(f.Path(), []byte("modified\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Write(map[string]string{"a": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, f.Path(), "modified\n")
+
+	if err := f.Write(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(f.Path()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("feature file was not removed: %v", err)
+	}
+	if err := f.Write(nil); err != nil {
+		t.Errorf("removing a missing feature file failed: %v", err)
+	}
+}
+
+func TestNilFile(t *testing.T) {
+	t.Parallel()
+
+	var f *File
+	if err := f.Write(map[string]string{"a": "1"}); err != nil {
+		t.Errorf("Write() on nil File = %v, want nil", err)
+	}
+}
+
+func TestBool(t *testing.T) {
+	t.Parallel()
+
+	if got := Bool(true); got != "true" {
+		t.Errorf("Bool(true) = %q, want true", got)
+	}
+	if got := Bool(false); got != "false" {
+		t.Errorf("Bool(false) = %q, want false", got)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("feature file = %q, want %q", data, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("feature file mode = %v, want 0644", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package healthcheck verifies that device nodes advertised to kubelet are usable.
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// Reasons of failed health checks, used as metric labels.
+const (
+	ReasonNotFound       = "not_found"
+	ReasonNotDevice      = "not_device"
+	ReasonNotCharDevice  = "not_char_device"
+	ReasonNotBlockDevice = "not_block_device"
+	ReasonDeviceNumber   = "unexpected_device_number"
+	ReasonPermission     = "permission_denied"
+	ReasonOpenFailed     = "open_failed"
+	ReasonProbeFailed    = "probe_failed"
+	ReasonUnsupportedAPI = "unsupported_api"
+	ReasonInUse          = "in_use"
+)
+
+// Error is returned by checkers when a device is unhealthy.
+type Error struct {
+	// Reason is a short, machine readable cause of the failure.
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Reason returns the reason of a failed health check, or ReasonProbeFailed for
+// errors that were not returned as *Error.
+func Reason(err error) string {
+	var herr *Error
+	if errors.As(err, &herr) {
+		return herr.Reason
+	}
+	return ReasonProbeFailed
+}
+
+// Checker verifies that the device node at path is usable.
+type Checker interface {
+	Check(path string) error
+}
+
+// CheckerFunc is an adapter to use ordinary functions as Checkers.
+type CheckerFunc func(path string) error
+
+func (f CheckerFunc) Check(path string) error {
+	return f(path)
+}
+
+// All returns a Checker that runs all checkers in order and stops at the first failure.
+func All(checkers ...Checker) Checker {
+	return CheckerFunc(func(path string) error {
+		for _, c := range checkers {
+			if err := c.Check(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CharDevice returns a Checker verifying that path is a character device with the given
+// major and minor numbers.
+func CharDevice(major, minor uint32) Checker {
+	return CheckerFunc(func(path string) error {
+		st, err := statCharDevice(path)
+		if err != nil {
+			return err
+		}
+
+		//nolint:unconvert // Rdev type differs between architectures
+		gotMajor, gotMinor := unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev))
+		if gotMajor != major || gotMinor != minor {
+			return &Error{
+				Reason: ReasonDeviceNumber,
+				Err: fmt.Errorf("%s has device number %d:%d, expected %d:%d",
+					path, gotMajor, gotMinor, major, minor),
+			}
+		}
+
+		return nil
+	})
+}
+
+// AnyCharDevice returns a Checker verifying that path is a character device, regardless
+// of its device number, e.g. for devices with dynamically allocated major numbers.
+func AnyCharDevice() Checker {
+	return CheckerFunc(func(path string) error {
+		_, err := statCharDevice(path)
+		return err
+	})
+}
+
+// AnyDevice returns a Checker verifying that path is a character or block device.
+func AnyDevice() Checker {
+	return CheckerFunc(func(path string) error {
+		var st unix.Stat_t
+		if err := unix.Stat(path, &st); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return &Error{Reason: ReasonNotFound, Err: err}
+			}
+			return &Error{Reason: ReasonProbeFailed, Err: err}
+		}
+
+		if mode := st.Mode & unix.S_IFMT; mode != unix.S_IFCHR && mode != unix.S_IFBLK {
+			return &Error{Reason: ReasonNotDevice, Err: fmt.Errorf("%s is not a device node", path)}
+		}
+
+		return nil
+	})
+}
+
+func statCharDevice(path string) (*unix.Stat_t, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &Error{Reason: ReasonNotFound, Err: err}
+		}
+		return nil, &Error{Reason: ReasonProbeFailed, Err: err}
+	}
+
+	if st.Mode&unix.S_IFMT != unix.S_IFCHR {
+		return nil, &Error{Reason: ReasonNotCharDevice, Err: fmt.Errorf("%s is not a character device", path)}
+	}
+
+	return &st, nil
+}
+
+// BlockDevice returns a Checker verifying that path is a block device with the given
+// major number. Minor numbers are not checked, as they depend on the device instance.
+func BlockDevice(major uint32) Checker {
+	return CheckerFunc(func(path string) error {
+		var st unix.Stat_t
+		if err := unix.Stat(path, &st); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return &Error{Reason: ReasonNotFound, Err: err}
+			}
+			return &Error{Reason: ReasonProbeFailed, Err: err}
+		}
+
+		if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+			return &Error{Reason: ReasonNotBlockDevice, Err: fmt.Errorf("%s is not a block device", path)}
+		}
+
+		//nolint:unconvert // Rdev type differs between architectures
+		if gotMajor := unix.Major(uint64(st.Rdev)); gotMajor != major {
+			return &Error{
+				Reason: ReasonDeviceNumber,
+				Err:    fmt.Errorf("%s has major device number %d, expected %d", path, gotMajor, major),
+			}
+		}
+
+		return nil
+	})
+}
+
+// OpenError classifies an error of opening a device.
+func OpenError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &Error{Reason: ReasonNotFound, Err: err}
+	case errors.Is(err, fs.ErrPermission):
+		return &Error{Reason: ReasonPermission, Err: err}
+	default:
+		return &Error{Reason: ReasonOpenFailed, Err: err}
+	}
+}
+
+// Open returns a Checker verifying that path can be opened with the given flags.
+// Probes are run against the opened file descriptor, e.g. to issue ioctls.
+func Open(flags int, probes ...func(fd int) error) Checker {
+	return CheckerFunc(func(path string) error {
+		fd, err := unix.Open(path, flags|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return OpenError(err)
+		}
+		defer unix.Close(fd) //nolint:errcheck // best effort call
+
+		for _, probe := range probes {
+			if err := probe(fd); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
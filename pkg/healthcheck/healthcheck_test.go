@@ -0,0 +1,144 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckers(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := file + ".missing"
+	probeErr := errors.New("probe")
+
+	// /dev/null is the character device 1:3 on Linux
+	for _, tc := range []struct {
+		name    string
+		checker Checker
+		path    string
+		reason  string
+	}{
+		{name: "char device", checker: CharDevice(1, 3), path: "/dev/null"},
+		{name: "char device number", checker: CharDevice(1, 5), path: "/dev/null", reason: ReasonDeviceNumber},
+		{name: "char device file", checker: CharDevice(1, 3), path: file, reason: ReasonNotCharDevice},
+		{name: "char device missing", checker: CharDevice(1, 3), path: missing, reason: ReasonNotFound},
+		{name: "any char device", checker: AnyCharDevice(), path: "/dev/null"},
+		{name: "any char device file", checker: AnyCharDevice(), path: file, reason: ReasonNotCharDevice},
+		{name: "any device", checker: AnyDevice(), path: "/dev/null"},
+		{name: "any device file", checker: AnyDevice(), path: file, reason: ReasonNotDevice},
+		{name: "any device missing", checker: AnyDevice(), path: missing, reason: ReasonNotFound},
+		{name: "block device char", checker: BlockDevice(7), path: "/dev/null", reason: ReasonNotBlockDevice},
+		{name: "block device file", checker: BlockDevice(7), path: file, reason: ReasonNotBlockDevice},
+		{name: "block device missing", checker: BlockDevice(7), path: missing, reason: ReasonNotFound},
+		{name: "open", checker: Open(unix.O_RDWR), path: "/dev/null"},
+		{name: "open missing", checker: Open(unix.O_RDWR), path: missing, reason: ReasonNotFound},
+		{
+			name:    "open probe",
+			checker: Open(unix.O_RDONLY, func(int) error { return nil }),
+			path:    "/dev/null",
+		},
+		{
+			name:    "open failed probe",
+			checker: Open(unix.O_RDONLY, func(int) error { return &Error{Reason: ReasonUnsupportedAPI, Err: probeErr} }),
+			path:    "/dev/null",
+			reason:  ReasonUnsupportedAPI,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.checker.Check(tc.path)
+			switch {
+			case tc.reason == "" && err != nil:
+				t.Errorf("Check(%q) = %v, want nil", tc.path, err)
+			case tc.reason != "" && Reason(err) != tc.reason:
+				t.Errorf("Check(%q) = %v, want reason %s", tc.path, err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestReason(t *testing.T) {
+	t.Parallel()
+
+	herr := &Error{Reason: ReasonInUse, Err: errors.New("busy")}
+	if got := Reason(fmt.Errorf("wrapped: %w", herr)); got != ReasonInUse {
+		t.Errorf("Reason() of wrapped error = %q, want %q", got, ReasonInUse)
+	}
+	if got := Reason(errors.New("plain")); got != ReasonProbeFailed {
+		t.Errorf("Reason() of plain error = %q, want %q", got, ReasonProbeFailed)
+	}
+	if !errors.Is(herr, herr.Err) {
+		t.Errorf("Error does not unwrap to %v", herr.Err)
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err    error
+		reason string
+	}{
+		{err: unix.ENOENT, reason: ReasonNotFound},
+		{err: unix.EACCES, reason: ReasonPermission},
+		{err: unix.EBUSY, reason: ReasonOpenFailed},
+	} {
+		if got := Reason(OpenError(tc.err)); got != tc.reason {
+			t.Errorf("Reason(OpenError(%v)) = %q, want %q", tc.err, got, tc.reason)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	checker := func(name string, err error) Checker {
+		return CheckerFunc(func(string) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+
+	failed := &Error{Reason: ReasonOpenFailed, Err: errors.New("failed")}
+	err := All(checker("a", nil), checker("b", failed), checker("c", nil)).Check("/dev/test")
+	if !errors.Is(err, failed) {
+		t.Errorf("Check() = %v, want %v", err, failed)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(calls, want) {
+		t.Errorf("checkers called: %v, want %v", calls, want)
+	}
+
+	calls = nil
+	if err := All(checker("a", nil), checker("b", nil)).Check("/dev/test"); err != nil {
+		t.Errorf("Check() = %v, want nil", err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(calls, want) {
+		t.Errorf("checkers called: %v, want %v", calls, want)
+	}
+}
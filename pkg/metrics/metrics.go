@@ -20,9 +20,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
+const namespace = "device_plugin"
+
 var (
 	Registry          = prometheus.NewRegistry()
-	GRPCServerMetrics = grpcprom.NewServerMetrics()
+	GRPCServerMetrics = grpcprom.NewServerMetrics(grpcprom.WithServerHandlingTimeHistogram())
 )
 
 var (
@@ -30,11 +32,105 @@ var (
 		Name: "grpc_server_panic_total",
 		Help: "Total number of panics in the gRPC server.",
 	})
+
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Total number of device plugin registrations with kubelet, by reason and result.",
+	}, []string{"resource", "reason", "result"})
+
+	Registered = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "registered",
+		Help:      "Whether the device plugin is currently registered with kubelet (1) or not (0).",
+	}, []string{"resource"})
+
+	AdvertisedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "advertised_devices",
+		Help:      "Number of devices advertised to kubelet.",
+	}, []string{"resource"})
+
+	HealthyDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "healthy_devices",
+		Help:      "Number of advertised devices reported as healthy.",
+	}, []string{"resource"})
+
+	UnhealthyDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "unhealthy_devices",
+		Help:      "Number of advertised devices reported as unhealthy.",
+	}, []string{"resource"})
+
+	UnhealthyTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unhealthy_transitions_total",
+		Help:      "Total number of times devices became unhealthy, by reason.",
+	}, []string{"resource", "reason"})
+
+	Allocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocations_total",
+		Help:      "Total number of Allocate calls, by result.",
+	}, []string{"resource", "result"})
+
+	AllocatedDevices = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "allocated_devices_total",
+		Help:      "Total number of devices allocated to containers.",
+	}, []string{"resource"})
+
+	AllocateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "allocate_duration_seconds",
+		Help:      "Latency of Allocate calls.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"resource"})
+
+	ListAndWatchStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "list_and_watch_streams",
+		Help:      "Number of open ListAndWatch streams.",
+	}, []string{"resource"})
+
+	DiscoveryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "discovery_errors_total",
+		Help:      "Total number of failed device discoveries.",
+	}, []string{"resource"})
+
+	KVMCapabilities = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kvm_capability",
+		Help:      "Result of KVM_CHECK_EXTENSION for probed KVM capabilities, 0 if not supported.",
+	}, []string{"capability"})
+
+	DeviceAllocations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "device_allocation",
+		Help:      "Devices allocated to containers, as reported by the kubelet PodResources API.",
+	}, []string{"resource", "device", "namespace", "pod", "container"})
 )
 
 func init() {
 	Registry.MustRegister(
 		collectors.NewGoCollector(),
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GRPCServerMetrics,
+		PanicCounter,
+		Registrations,
+		Registered,
+		AdvertisedDevices,
+		HealthyDevices,
+		UnhealthyDevices,
+		UnhealthyTransitions,
+		Allocations,
+		AllocatedDevices,
+		AllocateDuration,
+		ListAndWatchStreams,
+		DiscoveryErrors,
+		KVMCapabilities,
+		DeviceAllocations,
 	)
 }
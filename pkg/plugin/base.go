@@ -0,0 +1,140 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/events"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// Base implements the parts of a device plugin server shared by all servers. It holds
+// the discovered device list and publishes it to ListAndWatch streams once the CDI spec
+// of the devices is in place.
+//
+// Devices, SetDevices and Sync are not safe for concurrent use, servers embed Base and
+// guard them with their own lock, together with the state the CDI spec is built from.
+type Base struct {
+	log     *slog.Logger
+	cdi     cdi.Config
+	updates *Broadcaster
+
+	devs    []*v1beta1.Device
+	changed bool
+}
+
+// NewBase creates the Base of the server of the resource.
+func NewBase(resource string, cdiConfig cdi.Config, log *slog.Logger) Base {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	return Base{
+		log:     log,
+		cdi:     cdiConfig,
+		updates: NewBroadcaster(resource),
+		devs:    []*v1beta1.Device{},
+	}
+}
+
+// Devices returns the discovered device list.
+func (b *Base) Devices() []*v1beta1.Device {
+	return b.devs
+}
+
+// SetDevices replaces the discovered device list. It is published on the next call to Sync.
+func (b *Base) SetDevices(devs []*v1beta1.Device) {
+	b.devs = devs
+	b.changed = true
+}
+
+// Sync writes the CDI spec returned by spec and publishes the device list, if it changed
+// since the last call.
+func (b *Base) Sync(spec func() *cdi.Spec) {
+	if !b.changed {
+		return
+	}
+
+	if err := b.cdi.Sync(spec()); err != nil {
+		// devices are published on the next update, once their CDI spec is in place
+		b.log.Error("Failed to update CDI spec", "error", err)
+		return
+	}
+
+	b.updates.Publish(b.devs)
+	b.changed = false
+}
+
+// Advertised returns a copy of the device list last published to kubelet.
+func (b *Base) Advertised() []*v1beta1.Device {
+	return b.updates.Devices()
+}
+
+// CheckAllocation verifies the devices of all container requests against the advertised
+// devices, see CheckDevices.
+func (b *Base) CheckAllocation(req *v1beta1.AllocateRequest) error {
+	devs := b.Advertised()
+	for _, creq := range req.ContainerRequests {
+		if err := CheckDevices(devs, creq.DevicesIDs); err != nil {
+			b.log.Error("Rejecting allocation", "devices", creq.DevicesIDs, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ends all ListAndWatch streams.
+func (b *Base) Close() error {
+	return b.updates.Close()
+}
+
+// SetEventRecorder enables events on changes of the advertised devices.
+func (b *Base) SetEventRecorder(rec *events.Recorder) {
+	b.updates.SetEventRecorder(rec)
+}
+
+func (b *Base) GetDevicePluginOptions(
+	ctx context.Context,
+	_ *v1beta1.Empty,
+) (*v1beta1.DevicePluginOptions, error) {
+	return &v1beta1.DevicePluginOptions{
+		PreStartRequired:                false,
+		GetPreferredAllocationAvailable: false,
+	}, nil
+}
+
+func (b *Base) ListAndWatch(
+	_ *v1beta1.Empty,
+	lws v1beta1.DevicePlugin_ListAndWatchServer,
+) error {
+	return b.updates.ListAndWatch(lws)
+}
+
+func (b *Base) GetPreferredAllocation(
+	ctx context.Context,
+	req *v1beta1.PreferredAllocationRequest,
+) (*v1beta1.PreferredAllocationResponse, error) {
+	return &v1beta1.PreferredAllocationResponse{}, nil
+}
+
+func (b *Base) PreStartContainer(
+	ctx context.Context,
+	req *v1beta1.PreStartContainerRequest,
+) (*v1beta1.PreStartContainerResponse, error) {
+	return &v1beta1.PreStartContainerResponse{}, nil
+}
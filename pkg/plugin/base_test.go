@@ -0,0 +1,113 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func spec(devs []*v1beta1.Device) func() *cdi.Spec {
+	return func() *cdi.Spec {
+		spec := &cdi.Spec{Kind: "example.com/test"}
+		for _, dev := range devs {
+			spec.Devices = append(spec.Devices, cdi.Device{Name: dev.ID})
+		}
+		return spec
+	}
+}
+
+func TestBaseSync(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	blocked := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocked, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the spec directory cannot be created below a file
+	cdiConfig := cdi.Config{Mode: cdi.ModeCDI, SpecDir: filepath.Join(blocked, "cdi")}
+	b := NewBase("example.com/test", cdiConfig, slog.New(slog.DiscardHandler))
+	defer b.Close() //nolint:errcheck // best effort call
+
+	devs := devices("a")
+	b.SetDevices(devs)
+	b.Sync(spec(devs))
+	if got := b.Advertised(); len(got) != 0 {
+		t.Errorf("Advertised() = %v without CDI spec, want empty", got)
+	}
+
+	// devices are published on the next sync once the spec is written
+	b.cdi.SpecDir = filepath.Join(dir, "cdi")
+	b.Sync(spec(devs))
+	if got := b.Advertised(); !EqualDevices(got, devs) {
+		t.Errorf("Advertised() = %v, want %v", got, devs)
+	}
+	if _, err := os.ReadDir(b.cdi.SpecDir); err != nil {
+		t.Errorf("CDI spec was not written: %v", err)
+	}
+
+	// unchanged devices are not synced again
+	b.Sync(func() *cdi.Spec {
+		t.Error("spec built without changes")
+		return &cdi.Spec{}
+	})
+}
+
+func TestBaseCheckAllocation(t *testing.T) {
+	t.Parallel()
+
+	b := NewBase("example.com/test", cdi.Config{}, nil)
+	t.Cleanup(func() { b.Close() }) //nolint:errcheck // best effort call
+
+	devs := devices("a", "b")
+	devs[1].Health = v1beta1.Unhealthy
+	b.SetDevices(devs)
+	b.Sync(spec(devs))
+
+	for _, tc := range []struct {
+		name string
+		ids  [][]string
+		want codes.Code
+	}{
+		{name: "healthy", ids: [][]string{{"a"}}, want: codes.OK},
+		{name: "unhealthy", ids: [][]string{{"b"}}, want: codes.FailedPrecondition},
+		{name: "unknown", ids: [][]string{{"c"}}, want: codes.NotFound},
+		{name: "empty", ids: [][]string{{}}, want: codes.InvalidArgument},
+		{name: "any container", ids: [][]string{{"a"}, {"c"}}, want: codes.NotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &v1beta1.AllocateRequest{}
+			for _, ids := range tc.ids {
+				req.ContainerRequests = append(req.ContainerRequests, &v1beta1.ContainerAllocateRequest{DevicesIDs: ids})
+			}
+			if got := status.Code(b.CheckAllocation(req)); got != tc.want {
+				t.Errorf("CheckAllocation() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/events"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// Broadcaster fans out device list updates to any number of ListAndWatch streams.
+// Every stream receives the current device list first, followed by the latest list
+// after each change. Publishing never blocks: notifications are coalesced, so a slow
+// stream skips intermediate lists but always ends up with the latest one.
+type Broadcaster struct {
+	resource string
+	events   *events.Recorder
+
+	mu      sync.RWMutex
+	devices []*v1beta1.Device
+	subs    map[chan struct{}]struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// NewBroadcaster creates a Broadcaster for the resource, used to label its metrics.
+func NewBroadcaster(resource string) *Broadcaster {
+	return &Broadcaster{
+		resource: resource,
+		devices:  []*v1beta1.Device{},
+		subs:     map[chan struct{}]struct{}{},
+		done:     make(chan struct{}),
+	}
+}
+
+// SetEventRecorder enables events on changes of the device list. Changes published
+// before are not reported.
+func (b *Broadcaster) SetEventRecorder(rec *events.Recorder) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = rec
+}
+
+// Publish replaces the current device list and notifies all streams.
+func (b *Broadcaster) Publish(devices []*v1beta1.Device) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.record(b.devices, devices)
+	b.devices = CloneDevices(devices)
+	for sub := range b.subs {
+		notify(sub)
+	}
+
+	healthy := 0
+	for _, dev := range devices {
+		if dev.Health == v1beta1.Healthy {
+			healthy++
+		}
+	}
+	metrics.AdvertisedDevices.WithLabelValues(b.resource).Set(float64(len(devices)))
+	metrics.HealthyDevices.WithLabelValues(b.resource).Set(float64(healthy))
+	metrics.UnhealthyDevices.WithLabelValues(b.resource).Set(float64(len(devices) - healthy))
+}
+
+// record emits a single event per kind of change between the old and the new device
+// list, listing all affected devices.
+func (b *Broadcaster) record(old, devices []*v1beta1.Device) {
+	if b.events == nil {
+		return
+	}
+
+	health := make(map[string]string, len(old))
+	for _, dev := range old {
+		health[dev.ID] = dev.Health
+	}
+
+	var appeared, disappeared, healthy, unhealthy []string
+	for _, dev := range devices {
+		prev, ok := health[dev.ID]
+		delete(health, dev.ID)
+
+		switch {
+		case !ok:
+			appeared = append(appeared, dev.ID)
+			if dev.Health != v1beta1.Healthy {
+				unhealthy = append(unhealthy, dev.ID)
+			}
+		case prev != dev.Health && dev.Health == v1beta1.Healthy:
+			healthy = append(healthy, dev.ID)
+		case prev != dev.Health:
+			unhealthy = append(unhealthy, dev.ID)
+		}
+	}
+	for id := range health {
+		disappeared = append(disappeared, id)
+	}
+
+	if len(appeared) > 0 {
+		b.events.Normal(events.ReasonDevicesAppeared, "Devices of %s appeared: %s", b.resource, join(appeared))
+	}
+	if len(disappeared) > 0 {
+		b.events.Warning(events.ReasonDevicesDisappeared, "Devices of %s disappeared: %s", b.resource, join(disappeared))
+	}
+	if len(healthy) > 0 {
+		b.events.Normal(events.ReasonDevicesHealthy, "Devices of %s became healthy: %s", b.resource, join(healthy))
+	}
+	if len(unhealthy) > 0 {
+		b.events.Warning(events.ReasonDevicesUnhealthy, "Devices of %s are unhealthy: %s", b.resource, join(unhealthy))
+	}
+}
+
+func join(ids []string) string {
+	slices.Sort(ids)
+	return strings.Join(ids, ", ")
+}
+
+// Devices returns a copy of the current device list.
+func (b *Broadcaster) Devices() []*v1beta1.Device {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return CloneDevices(b.devices)
+}
+
+// Close ends all current and future ListAndWatch streams.
+func (b *Broadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
+
+// ListAndWatch sends the current device list and all subsequent updates to lws,
+// until the stream is cancelled or the broadcaster is closed.
+func (b *Broadcaster) ListAndWatch(lws v1beta1.DevicePlugin_ListAndWatchServer) error {
+	sub := b.subscribe()
+	defer b.unsubscribe(sub)
+
+	streams := metrics.ListAndWatchStreams.WithLabelValues(b.resource)
+	streams.Inc()
+	defer streams.Dec()
+
+	for {
+		select {
+		case <-lws.Context().Done():
+			return nil
+
+		case <-b.done:
+			return nil
+
+		case <-sub:
+			if err := b.send(lws); err != nil {
+				return fmt.Errorf("failed to send ListAndWatch response: %w", err)
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) send(lws v1beta1.DevicePlugin_ListAndWatchServer) (err error) {
+	devices := b.Devices()
+
+	healthy := 0
+	for _, dev := range devices {
+		if dev.GetHealth() == v1beta1.Healthy {
+			healthy++
+		}
+	}
+
+	_, span := tracing.Tracer().Start(lws.Context(), "ListAndWatch.Send", trace.WithAttributes(
+		tracing.ResourceKey.String(b.resource),
+		attribute.Int("device_plugin.devices.advertised", len(devices)),
+		attribute.Int("device_plugin.devices.healthy", healthy),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return lws.Send(&v1beta1.ListAndWatchResponse{Devices: devices})
+}
+
+func (b *Broadcaster) subscribe() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := make(chan struct{}, 1)
+	notify(sub) // initial list
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (b *Broadcaster) unsubscribe(sub chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub)
+}
+
+func notify(sub chan struct{}) {
+	select {
+	case sub <- struct{}{}:
+	default:
+		// notification is already pending
+	}
+}
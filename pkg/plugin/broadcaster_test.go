@@ -0,0 +1,371 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/events"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// stream is a fake ListAndWatch stream, passing every sent list to lists. Sends block
+// until the list is received.
+type stream struct {
+	grpc.ServerStream
+
+	ctx   context.Context
+	lists chan []*v1beta1.Device
+}
+
+func newStream(ctx context.Context) *stream {
+	return &stream{ctx: ctx, lists: make(chan []*v1beta1.Device)}
+}
+
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *stream) Send(resp *v1beta1.ListAndWatchResponse) error {
+	select {
+	case s.lists <- resp.Devices:
+	case <-s.ctx.Done():
+	}
+	return nil
+}
+
+// receive returns the next list sent to the stream.
+func (s *stream) receive(t *testing.T) []*v1beta1.Device {
+	t.Helper()
+
+	select {
+	case list := <-s.lists:
+		return list
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for device list")
+		return nil
+	}
+}
+
+// watch runs ListAndWatch for s in the background, the returned channel receives its result.
+func watch(b *Broadcaster, s *stream) <-chan error {
+	errs := make(chan error, 1)
+	go func() { errs <- b.ListAndWatch(s) }()
+	return errs
+}
+
+func devices(ids ...string) []*v1beta1.Device {
+	devs := []*v1beta1.Device{}
+	for _, id := range ids {
+		devs = append(devs, &v1beta1.Device{ID: id, Health: v1beta1.Healthy})
+	}
+	return devs
+}
+
+func TestBroadcasterLateSubscriber(t *testing.T) {
+	t.Parallel()
+
+	b := NewBroadcaster("test")
+	defer b.Close() //nolint:errcheck // best effort call
+	b.Publish(devices("a", "b"))
+
+	s := newStream(t.Context())
+	watch(b, s)
+
+	if got := s.receive(t); !EqualDevices(got, devices("a", "b")) {
+		t.Errorf("initial list = %v, want %v", got, devices("a", "b"))
+	}
+}
+
+func TestBroadcasterSlowStream(t *testing.T) {
+	t.Parallel()
+
+	b := NewBroadcaster("test")
+	defer b.Close() //nolint:errcheck // best effort call
+
+	s := newStream(t.Context())
+	watch(b, s)
+
+	// the stream is blocked sending the initial list, publishing must not wait for it
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := range 100 {
+			b.Publish(devices(string(rune('a' + i%26))))
+		}
+		b.Publish(devices("last"))
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a slow stream")
+	}
+
+	// intermediate lists are skipped, but the stream ends up with the latest one
+	for {
+		if got := s.receive(t); EqualDevices(got, devices("last")) {
+			break
+		}
+	}
+}
+
+func TestBroadcasterClose(t *testing.T) {
+	t.Parallel()
+
+	b := NewBroadcaster("test")
+
+	var results []<-chan error
+	for range 3 {
+		s := newStream(t.Context())
+		results = append(results, watch(b, s))
+		s.receive(t)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	for _, errs := range results {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf("ListAndWatch() = %v, want nil", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("ListAndWatch did not return after Close")
+		}
+	}
+
+	// streams started after Close end as well, at most after the initial list
+	s := newStream(t.Context())
+	errs := watch(b, s)
+	select {
+	case <-errs:
+	case <-s.lists:
+		<-errs
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListAndWatch did not return after Close")
+	}
+}
+
+func TestBroadcasterDevices(t *testing.T) {
+	t.Parallel()
+
+	b := NewBroadcaster("test")
+	defer b.Close() //nolint:errcheck // best effort call
+
+	if got := b.Devices(); len(got) != 0 {
+		t.Errorf("Devices() = %v before Publish, want empty", got)
+	}
+
+	devs := devices("a", "b")
+	devs[0].Topology = &v1beta1.TopologyInfo{Nodes: []*v1beta1.NUMANode{{ID: 1}}}
+	b.Publish(devs)
+
+	want := devices("a", "b")
+	want[0].Topology = &v1beta1.TopologyInfo{Nodes: []*v1beta1.NUMANode{{ID: 1}}}
+	if got := b.Devices(); !EqualDevices(got, want) {
+		t.Errorf("Devices() = %v, want %v", got, want)
+	}
+
+	// neither the published nor the returned list is shared with the broadcaster
+	devs[0].Health = v1beta1.Unhealthy
+	got := b.Devices()
+	got[1].Health = v1beta1.Unhealthy
+	got[0].Topology.Nodes[0].ID = 2
+	if got := b.Devices(); !EqualDevices(got, want) {
+		t.Errorf("Devices() = %v after modifying copies, want %v", got, want)
+	}
+}
+
+func TestBroadcasterEvents(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	rec := events.New(client, "node-1", nil)
+	t.Cleanup(rec.Shutdown)
+
+	b := NewBroadcaster("example.com/events")
+	t.Cleanup(func() { b.Close() }) //nolint:errcheck // best effort call
+	b.SetEventRecorder(rec)
+
+	b.Publish([]*v1beta1.Device{
+		{ID: "a", Health: v1beta1.Healthy},
+		{ID: "b", Health: v1beta1.Unhealthy},
+	})
+	b.Publish([]*v1beta1.Device{
+		{ID: "a", Health: v1beta1.Unhealthy},
+		{ID: "b", Health: v1beta1.Healthy},
+	})
+	b.Publish([]*v1beta1.Device{
+		{ID: "b", Health: v1beta1.Healthy},
+	})
+	// unchanged lists are not reported
+	b.Publish([]*v1beta1.Device{
+		{ID: "b", Health: v1beta1.Healthy},
+	})
+
+	want := map[string]string{
+		"Devices of example.com/events appeared: a, b":    events.ReasonDevicesAppeared,
+		"Devices of example.com/events are unhealthy: b":  events.ReasonDevicesUnhealthy,
+		"Devices of example.com/events are unhealthy: a":  events.ReasonDevicesUnhealthy,
+		"Devices of example.com/events became healthy: b": events.ReasonDevicesHealthy,
+		"Devices of example.com/events disappeared: a":    events.ReasonDevicesDisappeared,
+	}
+
+	var got []corev1.Event
+	deadline := time.Now().Add(10 * time.Second)
+	for len(got) < len(want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		list, err := client.CoreV1().Events("").List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = list.Items
+	}
+
+	for _, event := range got {
+		reason, ok := want[event.Message]
+		if !ok || event.Reason != reason || event.Count != 1 {
+			t.Errorf("unexpected event %s %q (count %d)", event.Reason, event.Message, event.Count)
+		}
+		if obj := event.InvolvedObject; obj.Kind != "Node" || obj.Name != "node-1" {
+			t.Errorf("event %q is emitted on %s %q, want Node %q", event.Message, obj.Kind, obj.Name, "node-1")
+		}
+	}
+}
+
+func TestBroadcasterMetrics(t *testing.T) {
+	t.Parallel()
+
+	const resource = "example.com/metrics"
+	b := NewBroadcaster(resource)
+	t.Cleanup(func() { b.Close() }) //nolint:errcheck // best effort call
+
+	for _, tc := range []struct {
+		devices                     []*v1beta1.Device
+		advertised, healthy, broken float64
+	}{
+		{
+			devices: []*v1beta1.Device{
+				{ID: "a", Health: v1beta1.Healthy},
+				{ID: "b", Health: v1beta1.Unhealthy},
+				{ID: "c", Health: v1beta1.Healthy},
+			},
+			advertised: 3, healthy: 2, broken: 1,
+		},
+		{devices: devices("a"), advertised: 1, healthy: 1},
+		{devices: devices()},
+	} {
+		b.Publish(tc.devices)
+
+		for _, m := range []struct {
+			name  string
+			gauge *prometheus.GaugeVec
+			want  float64
+		}{
+			{name: "advertised_devices", gauge: metrics.AdvertisedDevices, want: tc.advertised},
+			{name: "healthy_devices", gauge: metrics.HealthyDevices, want: tc.healthy},
+			{name: "unhealthy_devices", gauge: metrics.UnhealthyDevices, want: tc.broken},
+		} {
+			if got := testutil.ToFloat64(m.gauge.WithLabelValues(resource)); got != m.want {
+				t.Errorf("%s after publishing %v = %v, want %v", m.name, tc.devices, got, m.want)
+			}
+		}
+	}
+}
+
+// sendStream is a fake ListAndWatch stream, failing sends with err.
+type sendStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+	err error
+}
+
+func (s *sendStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sendStream) Send(*v1beta1.ListAndWatchResponse) error {
+	return s.err
+}
+
+// TestBroadcasterSendSpans is not parallel, as it replaces the global tracer provider.
+func TestBroadcasterSendSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	b := NewBroadcaster("example.com/spans")
+	t.Cleanup(func() { b.Close() }) //nolint:errcheck // best effort call
+	b.Publish([]*v1beta1.Device{
+		{ID: "a", Health: v1beta1.Healthy},
+		{ID: "b", Health: v1beta1.Unhealthy},
+	})
+
+	if err := b.send(&sendStream{ctx: t.Context()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.send(&sendStream{ctx: t.Context(), err: errors.New("closed")}); err == nil {
+		t.Fatal("send() = nil for failing stream, want error")
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	for i, code := range []codes.Code{codes.Unset, codes.Error} {
+		span := spans[i]
+		if span.Name() != "ListAndWatch.Send" {
+			t.Errorf("span %d = %q, want ListAndWatch.Send", i, span.Name())
+		}
+		attrs := attribute.NewSet(span.Attributes()...)
+		if got, _ := attrs.Value(tracing.ResourceKey); got.AsString() != "example.com/spans" {
+			t.Errorf("resource of span %d = %q, want example.com/spans", i, got.AsString())
+		}
+		if got, _ := attrs.Value("device_plugin.devices.advertised"); got.AsInt64() != 2 {
+			t.Errorf("advertised devices of span %d = %d, want 2", i, got.AsInt64())
+		}
+		if got, _ := attrs.Value("device_plugin.devices.healthy"); got.AsInt64() != 1 {
+			t.Errorf("healthy devices of span %d = %d, want 1", i, got.AsInt64())
+		}
+		if got := span.Status().Code; got != code {
+			t.Errorf("status of span %d = %v, want %v", i, got, code)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// CheckDevices verifies that every requested ID refers to one of the advertised devices
+// and that the device is healthy. The returned error is a gRPC status error, so it can be
+// returned from Allocate as is.
+func CheckDevices(devices []*v1beta1.Device, ids []string) error {
+	if len(ids) == 0 {
+		return status.Error(codes.InvalidArgument, "no devices requested")
+	}
+
+	health := make(map[string]string, len(devices))
+	for _, dev := range devices {
+		health[dev.ID] = dev.Health
+	}
+
+	for _, id := range ids {
+		h, ok := health[id]
+		if !ok {
+			return status.Errorf(codes.NotFound, "unknown device %q", id)
+		}
+		if h != v1beta1.Healthy {
+			return status.Errorf(codes.FailedPrecondition, "device %q is %s", id, h)
+		}
+	}
+
+	return nil
+}
+
+// EqualDevices reports whether both device lists advertise the same devices with the
+// same health and topology, so that unchanged lists are not sent to kubelet again.
+func EqualDevices(a, b []*v1beta1.Device) bool {
+	return slices.EqualFunc(a, b, func(x, y *v1beta1.Device) bool {
+		return x.ID == y.ID && x.Health == y.Health && slices.Equal(numaNodes(x), numaNodes(y))
+	})
+}
+
+func numaNodes(dev *v1beta1.Device) []int64 {
+	ids := []int64{}
+	for _, node := range dev.GetTopology().GetNodes() {
+		ids = append(ids, node.GetID())
+	}
+	return ids
+}
+
+// CloneDevices returns a deep copy of the device list, so that neither side can change
+// devices seen by the other.
+func CloneDevices(devices []*v1beta1.Device) []*v1beta1.Device {
+	clone := make([]*v1beta1.Device, 0, len(devices))
+	for _, dev := range devices {
+		c := &v1beta1.Device{ID: dev.ID, Health: dev.Health}
+		if dev.Topology != nil {
+			c.Topology = &v1beta1.TopologyInfo{}
+			for _, node := range dev.Topology.Nodes {
+				c.Topology.Nodes = append(c.Topology.Nodes, &v1beta1.NUMANode{ID: node.GetID()})
+			}
+		}
+		clone = append(clone, c)
+	}
+	return clone
+}
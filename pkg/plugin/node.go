@@ -0,0 +1,223 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const rwPerm = "rw"
+
+// NodeConfig describes a single host device node shared by a number of devices.
+type NodeConfig struct {
+	// Namespace and Name make up the resource name. Name is also the prefix of the
+	// device IDs and the name of the socket.
+	Namespace string
+	Name      string
+	// Kind names the device in logs, e.g. TUN.
+	Kind string
+	// Path of the device node, injected at the same path into containers.
+	Path string
+	// Devices is the number of advertised devices, i.e. of containers sharing the node.
+	Devices uint
+	// Checker verifies that the device node is usable.
+	Checker healthcheck.Checker
+	// Missing, if set, is called when the device node does not exist. If it returns an
+	// error, the devices are advertised as unhealthy instead of not at all, e.g. when
+	// the kernel module providing the node is not available.
+	Missing func() error
+	// Annotations are passed to runtimes with the allocated containers and CDI devices.
+	Annotations map[string]string
+}
+
+// NodeServer is a device plugin server advertising a single host device node, like
+// /dev/net/tun, as a number of devices, so that as many containers can share it.
+type NodeServer struct {
+	Base
+
+	log *slog.Logger
+	cfg NodeConfig
+	cdi cdi.Config
+
+	mu sync.RWMutex
+}
+
+// NewNodeServer creates the server of the device node and runs the initial discovery.
+func NewNodeServer(cfg NodeConfig, cdiConfig cdi.Config, log *slog.Logger) *NodeServer {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	s := &NodeServer{
+		log: log,
+		cfg: cfg,
+		cdi: cdiConfig,
+	}
+	s.Base = NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		log.Warn("No " + cfg.Kind + " device found")
+	}
+	return s
+}
+
+// Discover checks whether the device node is present and healthy and rebuilds the list
+// of advertised devices. Changes are published to kubelet on the next call to Update.
+func (s *NodeServer) Discover() error {
+	var herr error
+	present := false
+
+	_, err := os.Stat(s.cfg.Path)
+	switch {
+	case err == nil:
+		present = true
+		herr = s.cfg.Checker.Check(s.cfg.Path)
+	case errors.Is(err, fs.ErrNotExist):
+		// device is not present (yet), advertise no devices unless the cause is known
+		if s.cfg.Missing != nil {
+			herr = s.cfg.Missing()
+		}
+	default:
+		return fmt.Errorf("failed to stat %s: %w", s.cfg.Path, err)
+	}
+
+	devs := []*v1beta1.Device{}
+	if present || herr != nil {
+		health := v1beta1.Healthy
+		if herr != nil {
+			health = v1beta1.Unhealthy
+		}
+		for i := uint(0); i < s.cfg.Devices; i++ {
+			devs = append(devs, &v1beta1.Device{
+				ID:     fmt.Sprintf("%s%d", s.cfg.Name, i),
+				Health: health,
+			})
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if EqualDevices(devs, s.Devices()) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn(s.cfg.Kind + " device disappeared")
+	case herr != nil:
+		reason := healthcheck.Reason(herr)
+		s.log.Warn(s.cfg.Kind+" device is unhealthy", "reason", reason, "error", herr)
+		metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+	case len(s.Devices()) == 0:
+		s.log.Info("Discovered "+s.cfg.Kind+" device", "devices", len(devs))
+	default:
+		s.log.Info(s.cfg.Kind + " device is healthy")
+	}
+
+	s.SetDevices(devs)
+	return nil
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *NodeServer) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *NodeServer) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name:        dev.ID,
+			Annotations: s.cfg.Annotations,
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        s.cfg.Path,
+						HostPath:    s.cfg.Path,
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the device node path watched for changes by discovery.
+func (s *NodeServer) WatchPaths() []string {
+	return []string{s.cfg.Path}
+}
+
+func (s *NodeServer) Name() string {
+	return path.Join(s.cfg.Namespace, s.cfg.Name)
+}
+
+func (s *NodeServer) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, s.cfg.Name+".sock"))
+}
+
+func (s *NodeServer) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		cres := &v1beta1.ContainerAllocateResponse{
+			Annotations: s.cfg.Annotations,
+		}
+		if s.cdi.Mode.DeviceSpec() {
+			cres.Devices = []*v1beta1.DeviceSpec{
+				{
+					ContainerPath: s.cfg.Path,
+					HostPath:      s.cfg.Path,
+					Permissions:   rwPerm,
+				},
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func nodeConfig(t *testing.T, checker healthcheck.Checker) NodeConfig {
+	t.Helper()
+
+	return NodeConfig{
+		Namespace: "example.com",
+		Name:      "test",
+		Kind:      "test",
+		Path:      filepath.Join(t.TempDir(), "test"),
+		Devices:   2,
+		Checker:   checker,
+	}
+}
+
+func health(devs []*v1beta1.Device) []string {
+	res := make([]string, 0, len(devs))
+	for _, dev := range devs {
+		res = append(res, dev.ID+"="+dev.Health)
+	}
+	return res
+}
+
+func TestNodeServerDiscover(t *testing.T) {
+	t.Parallel()
+
+	var herr error
+	cfg := nodeConfig(t, healthcheck.CheckerFunc(func(string) error { return herr }))
+	s := NewNodeServer(cfg, cdi.Config{Mode: cdi.ModeDeviceSpec}, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	if got := s.Advertised(); len(got) != 0 {
+		t.Errorf("Advertised() = %v without device node, want empty", got)
+	}
+
+	if err := os.WriteFile(cfg.Path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{name: "present", want: []string{"test0=Healthy", "test1=Healthy"}},
+		{name: "unhealthy", err: errors.New("broken"), want: []string{"test0=Unhealthy", "test1=Unhealthy"}},
+		{name: "recovered", want: []string{"test0=Healthy", "test1=Healthy"}},
+	} {
+		herr = step.err
+		if err := s.Discover(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		s.Update()
+		if got := health(s.Advertised()); !slices.Equal(got, step.want) {
+			t.Errorf("%s: advertised %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	if err := os.Remove(cfg.Path); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	s.Update()
+	if got := s.Advertised(); len(got) != 0 {
+		t.Errorf("Advertised() = %v after removal, want empty", got)
+	}
+}
+
+func TestNodeServerMissing(t *testing.T) {
+	t.Parallel()
+
+	cfg := nodeConfig(t, healthcheck.CheckerFunc(func(string) error { return nil }))
+	cfg.Missing = func() error { return errors.New("module not loaded") }
+	s := NewNodeServer(cfg, cdi.Config{Mode: cdi.ModeDeviceSpec}, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	want := []string{"test0=Unhealthy", "test1=Unhealthy"}
+	if got := health(s.Advertised()); !slices.Equal(got, want) {
+		t.Errorf("advertised %v, want %v", got, want)
+	}
+}
+
+func TestNodeServerAllocate(t *testing.T) {
+	t.Parallel()
+
+	cfg := nodeConfig(t, healthcheck.CheckerFunc(func(string) error { return nil }))
+	cfg.Annotations = map[string]string{"example.com/key": "value"}
+	if err := os.WriteFile(cfg.Path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewNodeServer(cfg, cdi.Config{Mode: cdi.ModeBoth, SpecDir: t.TempDir()}, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"test1"}},
+			{DevicesIDs: []string{"test0"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range []string{"test1", "test0"} {
+		cres := res.ContainerResponses[i]
+		if len(cres.Devices) != 1 || cres.Devices[0].HostPath != cfg.Path || cres.Devices[0].ContainerPath != cfg.Path {
+			t.Errorf("Devices of container %d = %v, want %s", i, cres.Devices, cfg.Path)
+		}
+		name := cdi.QualifiedName(s.Name(), id)
+		if len(cres.CDIDevices) != 1 || cres.CDIDevices[0].Name != name {
+			t.Errorf("CDI devices of container %d = %v, want %s", i, cres.CDIDevices, name)
+		}
+		if got := cres.Annotations["example.com/key"]; got != "value" {
+			t.Errorf("annotation of container %d = %q, want value", i, got)
+		}
+	}
+
+	if _, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"test2"}}},
+	}); err == nil {
+		t.Error("Allocate() of unknown device succeeded")
+	}
+}
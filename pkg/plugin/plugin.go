@@ -18,22 +18,41 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/tracing"
 
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
+// Reasons for (re-)registering the device plugin with kubelet.
+const (
+	ReasonStartup        = "startup"
+	ReasonKubeletRestart = "kubelet_restart"
+	ReasonSocketRemoved  = "socket_removed"
+)
+
 type Plugin struct {
-	log *slog.Logger
+	log           *slog.Logger
+	kubeletSocket string
+
+	mu     sync.Mutex
+	socket os.FileInfo
 }
 
 func New(log *slog.Logger) *Plugin {
@@ -42,14 +61,28 @@ func New(log *slog.Logger) *Plugin {
 	}
 
 	return &Plugin{
-		log: log,
+		log:           log,
+		kubeletSocket: v1beta1.KubeletSocket,
 	}
 }
 
+// SetKubeletSocket sets the path of the kubelet registration socket, v1beta1.KubeletSocket
+// by default. It must be called before WatchKubelet.
+func (p *Plugin) SetKubeletSocket(socket string) {
+	p.kubeletSocket = socket
+}
+
 func (p *Plugin) DevicePluginServer(plugin v1beta1.DevicePluginServer) *grpc.Server {
+	var resource string
+	if named, ok := plugin.(interface{ Name() string }); ok {
+		resource = named.Name()
+	}
+
 	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.ChainUnaryInterceptor(
 			metrics.GRPCServerMetrics.UnaryServerInterceptor(),
+			allocateMetrics(resource),
 			logging.UnaryServerInterceptor(&grpcLogger{log: p.log}),
 			recovery.UnaryServerInterceptor(recovery.WithRecoveryHandler(grpcRecovery(p.log))),
 		),
@@ -68,6 +101,8 @@ func (p *Plugin) DevicePluginServer(plugin v1beta1.DevicePluginServer) *grpc.Ser
 	return srv
 }
 
+// RegisterDevicePlugin waits until the plugin socket is serving and registers the plugin
+// with kubelet. Retries are recorded as events of the span in ctx, if any.
 func (p *Plugin) RegisterDevicePlugin(ctx context.Context, name, socket string) error {
 	if err := p.waitForPluginReady(ctx, name, socket); err != nil {
 		return fmt.Errorf("plugin not ready: %w", err)
@@ -80,14 +115,137 @@ func (p *Plugin) RegisterDevicePlugin(ctx context.Context, name, socket string)
 	return nil
 }
 
-func (p *Plugin) connectGRPCWithRetry(socket string) (*grpc.ClientConn, error) {
+// SetSocket records the socket the plugin is serving on, so that WatchKubelet can tell
+// removals of that socket apart from the removals made by the plugin itself.
+func (p *Plugin) SetSocket(socket string) error {
+	endpointURL, err := url.Parse(socket)
+	if err != nil {
+		return fmt.Errorf("unable to parse plugin endpoint: %w", err)
+	}
+	if endpointURL.Scheme != "unix" {
+		return nil
+	}
+
+	fi, err := os.Stat(endpointURL.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat plugin socket: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.socket = fi
+	return nil
+}
+
+// ClearSocket forgets the socket recorded by SetSocket, before the plugin removes it.
+func (p *Plugin) ClearSocket() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.socket = nil
+}
+
+// socketRemoved reports whether the socket recorded by SetSocket no longer exists at
+// path. Sockets removed while none is recorded were removed by the plugin itself, and
+// sockets recreated since belong to the plugin as well.
+func (p *Plugin) socketRemoved(path string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.socket == nil {
+		return false
+	}
+	fi, err := os.Stat(path)
+	return err != nil || !os.SameFile(fi, p.socket)
+}
+
+// WatchKubelet watches the device plugin directory and sends a reason on the returned
+// channel whenever the plugin has to recreate its socket and register again: either
+// kubelet.sock was created (kubelet restarted) or the plugin socket recorded by
+// SetSocket was removed by someone else. Pending notifications are coalesced, so the
+// receiver never blocks the watcher.
+func (p *Plugin) WatchKubelet(ctx context.Context, socket string) (<-chan string, error) {
+	endpointURL, err := url.Parse(socket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse plugin endpoint: %w", err)
+	}
+	socketPath := filepath.Clean(endpointURL.Path)
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filesystem watcher: %w", err)
+	}
+
+	kubeletSocket := filepath.Clean(p.kubeletSocket)
+	dirs := []string{filepath.Dir(kubeletSocket)}
+	if dir := filepath.Dir(socketPath); dir != dirs[0] {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		if err := fsw.Add(dir); err != nil {
+			fsw.Close() //nolint:errcheck // best effort call
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	restart := make(chan string, 1)
+
+	go func() {
+		defer fsw.Close() //nolint:errcheck // best effort call
+
+		for {
+			var reason string
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case err, ok := <-fsw.Errors:
+				if !ok {
+					return
+				}
+				p.log.Error("Kubelet socket watcher failed", "error", err)
+				continue
+
+			case ev, ok := <-fsw.Events:
+				if !ok {
+					return
+				}
+
+				switch name := filepath.Clean(ev.Name); {
+				case name == kubeletSocket && ev.Has(fsnotify.Create):
+					reason = ReasonKubeletRestart
+				case name == socketPath && ev.Has(fsnotify.Remove|fsnotify.Rename):
+					if !p.socketRemoved(socketPath) {
+						// removed by our own restart or startup
+						continue
+					}
+					reason = ReasonSocketRemoved
+				default:
+					continue
+				}
+			}
+
+			p.log.Info("Device plugin needs to be re-registered", "reason", reason)
+			select {
+			case restart <- reason:
+			default:
+				// restart is already pending
+			}
+		}
+	}()
+
+	return restart, nil
+}
+
+func (p *Plugin) connectGRPCWithRetry(ctx context.Context, socket string) (*grpc.ClientConn, error) {
 	var conn *grpc.ClientConn
 
-	err := p.retry(func() error {
+	err := p.retry(ctx, func() error {
 		var err error
 		conn, err = grpc.NewClient(
 			socket,
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		)
 		return err
 	})
@@ -95,7 +253,7 @@ func (p *Plugin) connectGRPCWithRetry(socket string) (*grpc.ClientConn, error) {
 	return conn, err
 }
 
-func (p *Plugin) retry(op func() error) error {
+func (p *Plugin) retry(ctx context.Context, op func() error) error {
 	baseDelay := 100 * time.Millisecond // Initial backoff delay
 	maxDelay := 5 * time.Second         // Maximum backoff delay
 	maxRetries := 5                     // Maximum retry attempts
@@ -113,7 +271,14 @@ func (p *Plugin) retry(op func() error) error {
 		}
 
 		p.log.Debug("Failure, retrying", "backoff", backoffDelay)
-		time.Sleep(backoffDelay)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.String("backoff", backoffDelay.String()),
+			attribute.String("error", err.Error()),
+		))
+		if err := Sleep(ctx, backoffDelay); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf(
@@ -122,17 +287,31 @@ func (p *Plugin) retry(op func() error) error {
 	)
 }
 
+// Sleep waits for the duration, or until ctx is done, in which case the error of ctx is
+// returned.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
 func (p *Plugin) waitForPluginReady(ctx context.Context, name, socket string) error {
 	p.log.Info("Waiting for socket ready", "name", name, "socket", socket)
 
-	conn, err := p.connectGRPCWithRetry(socket)
+	conn, err := p.connectGRPCWithRetry(ctx, socket)
 	if err != nil {
 		return fmt.Errorf("failed to create connection to local gRPC server: %w", err)
 	}
 	defer conn.Close() //nolint:errcheck // best effort call
 
 	health := grpc_health_v1.NewHealthClient(conn)
-	err = p.retry(func() error {
+	err = p.retry(ctx, func() error {
 		res, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: name})
 		if err != nil {
 			return err
@@ -153,10 +332,10 @@ func (p *Plugin) registerWithKubelet(ctx context.Context, name, socket string) e
 	p.log.Info("Registering device plugin",
 		"name", name,
 		"socket", socket,
-		"kubelet", v1beta1.KubeletSocket,
+		"kubelet", p.kubeletSocket,
 	)
 
-	conn, err := p.connectGRPCWithRetry(fmt.Sprintf("unix://%s", v1beta1.KubeletSocket))
+	conn, err := p.connectGRPCWithRetry(ctx, fmt.Sprintf("unix://%s", p.kubeletSocket))
 	if err != nil {
 		return fmt.Errorf("failed to connect to kubelet: %v", err)
 	}
@@ -174,12 +353,67 @@ func (p *Plugin) registerWithKubelet(ctx context.Context, name, socket string) e
 	return nil
 }
 
+// allocateMetrics records latency and results of Allocate calls, labeled by resource name.
+// The requested devices are also added to the span of the call, if it is traced.
+func allocateMetrics(resource string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		areq, ok := req.(*v1beta1.AllocateRequest)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		ids := []string{}
+		for _, creq := range areq.GetContainerRequests() {
+			ids = append(ids, creq.GetDevicesIDs()...)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(
+			tracing.ResourceKey.String(resource),
+			tracing.DevicesKey.StringSlice(ids),
+		)
+
+		start := time.Now()
+		res, err := handler(ctx, req)
+		metrics.AllocateDuration.WithLabelValues(resource).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			metrics.Allocations.WithLabelValues(resource, "failure").Inc()
+			return res, err
+		}
+
+		metrics.Allocations.WithLabelValues(resource, "success").Inc()
+		metrics.AllocatedDevices.WithLabelValues(resource).Add(float64(len(ids)))
+
+		return res, nil
+	}
+}
+
 type grpcLogger struct {
 	log *slog.Logger
 }
 
-func (g *grpcLogger) Log(ctx context.Context, _ logging.Level, msg string, kv ...any) {
-	g.log.Debug(msg, kv...)
+func (g *grpcLogger) Log(ctx context.Context, level logging.Level, msg string, kv ...any) {
+	g.log.Log(ctx, slogLevel(level), msg, kv...)
+}
+
+// slogLevel maps grpc-middleware log levels to slog levels.
+func slogLevel(level logging.Level) slog.Level {
+	switch level {
+	case logging.LevelDebug:
+		return slog.LevelDebug
+	case logging.LevelInfo:
+		return slog.LevelInfo
+	case logging.LevelWarn:
+		return slog.LevelWarn
+	case logging.LevelError:
+		return slog.LevelError
+	default:
+		return slog.Level(level)
+	}
 }
 
 func grpcRecovery(log *slog.Logger) func(p any) (err error) {
@@ -0,0 +1,182 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func listen(t *testing.T, path string) net.Listener {
+	t.Helper()
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { lis.Close() }) //nolint:errcheck // best effort call
+	return lis
+}
+
+func TestSocketRemoved(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+	p := New(slog.New(slog.DiscardHandler))
+
+	lis := listen(t, path)
+	if p.socketRemoved(path) {
+		t.Error("socketRemoved() = true before SetSocket, want false")
+	}
+
+	if err := p.SetSocket("unix://" + path); err != nil {
+		t.Fatalf("SetSocket() = %v", err)
+	}
+	if p.socketRemoved(path) {
+		t.Error("socketRemoved() = true while serving, want false")
+	}
+
+	// own restart: the socket is cleared before it is removed and recreated
+	p.ClearSocket()
+	lis.Close()     //nolint:errcheck // best effort call
+	os.Remove(path) //nolint:errcheck // best effort call
+	if p.socketRemoved(path) {
+		t.Error("socketRemoved() = true after own removal, want false")
+	}
+	listen(t, path)
+	if err := p.SetSocket("unix://" + path); err != nil {
+		t.Fatalf("SetSocket() = %v", err)
+	}
+	if p.socketRemoved(path) {
+		t.Error("socketRemoved() = true after own restart, want false")
+	}
+
+	// external removal
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove socket: %v", err)
+	}
+	if !p.socketRemoved(path) {
+		t.Error("socketRemoved() = false after external removal, want true")
+	}
+
+	// replaced by someone else
+	listen(t, path)
+	if !p.socketRemoved(path) {
+		t.Error("socketRemoved() = false after replacement, want true")
+	}
+}
+
+func TestSetSocket(t *testing.T) {
+	t.Parallel()
+
+	p := New(slog.New(slog.DiscardHandler))
+	if err := p.SetSocket("tcp://127.0.0.1:8080"); err != nil {
+		t.Errorf("SetSocket() = %v for non-unix socket, want nil", err)
+	}
+	if err := p.SetSocket("unix://" + filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Error("SetSocket() = nil for missing socket, want error")
+	}
+}
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	if err := Sleep(t.Context(), time.Millisecond); err != nil {
+		t.Errorf("Sleep() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAllocateMetrics(t *testing.T) {
+	t.Parallel()
+
+	const resource = "example.com/allocate"
+	interceptor := allocateMetrics(resource)
+	req := &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"a", "b"}},
+			{DevicesIDs: []string{"c"}},
+		},
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/v1beta1.DevicePlugin/Allocate"}
+
+	for _, err := range []error{
+		nil,
+		status.Error(codes.NotFound, "unknown device"),
+		status.Error(codes.InvalidArgument, "duplicate device"),
+		nil,
+	} {
+		_, gotErr := interceptor(t.Context(), req, info, func(context.Context, any) (any, error) {
+			return &v1beta1.AllocateResponse{}, err
+		})
+		if !errors.Is(gotErr, err) {
+			t.Errorf("interceptor returned %v, want %v", gotErr, err)
+		}
+	}
+
+	for _, m := range []struct {
+		name      string
+		collector prometheus.Collector
+		want      float64
+	}{
+		{name: "success", collector: metrics.Allocations.WithLabelValues(resource, "success"), want: 2},
+		{name: "failure", collector: metrics.Allocations.WithLabelValues(resource, "failure"), want: 2},
+		{name: "allocated devices", collector: metrics.AllocatedDevices.WithLabelValues(resource), want: 6},
+	} {
+		if got := testutil.ToFloat64(m.collector); got != m.want {
+			t.Errorf("%s = %v, want %v", m.name, got, m.want)
+		}
+	}
+
+	var hist dto.Metric
+	if err := metrics.AllocateDuration.WithLabelValues(resource).(prometheus.Metric).Write(&hist); err != nil {
+		t.Fatal(err)
+	}
+	if got := hist.GetHistogram().GetSampleCount(); got != 4 {
+		t.Errorf("allocate_duration_seconds count = %d, want 4", got)
+	}
+
+	// other calls are passed through without being recorded
+	if _, err := interceptor(t.Context(), &v1beta1.Empty{}, info, func(context.Context, any) (any, error) {
+		return &v1beta1.DevicePluginOptions{}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.Allocations.WithLabelValues(resource, "success")); got != 2 {
+		t.Errorf("success = %v after other call, want 2", got)
+	}
+}
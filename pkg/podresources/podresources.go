@@ -0,0 +1,208 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package podresources tracks which containers hold the devices advertised by the
+// plugins, using the kubelet PodResources API.
+package podresources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	// DefaultSocket is the path of the kubelet PodResources API socket.
+	DefaultSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	callTimeout = 10 * time.Second
+	maxMsgSize  = 16 * 1024 * 1024
+)
+
+// Owner identifies the container a device is allocated to.
+type Owner struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// Allocations maps resource names to device IDs and their owners.
+type Allocations map[string]map[string]Owner
+
+// Subscriber is implemented by servers that depend on the allocations of devices.
+type Subscriber interface {
+	UpdateAllocations(allocations Allocations)
+}
+
+// Reconciler periodically lists pod resources from kubelet and maintains the owners of
+// devices of the served resources.
+type Reconciler struct {
+	log       *slog.Logger
+	socket    string
+	interval  time.Duration
+	resources map[string]struct{}
+
+	mu          sync.RWMutex
+	allocations Allocations
+	subscribers []Subscriber
+}
+
+var _ http.Handler = (*Reconciler)(nil)
+
+func New(socket string, interval time.Duration, resources []string, log *slog.Logger) *Reconciler {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if socket == "" {
+		socket = DefaultSocket
+	}
+
+	r := &Reconciler{
+		log:         log,
+		socket:      socket,
+		interval:    interval,
+		resources:   map[string]struct{}{},
+		allocations: Allocations{},
+	}
+	for _, name := range resources {
+		r.resources[name] = struct{}{}
+	}
+	return r
+}
+
+// Subscribe registers s to be notified with the allocations after every reconciliation.
+func (r *Reconciler) Subscribe(s Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, s)
+}
+
+// Run reconciles allocations every interval until ctx is cancelled.
+func (r *Reconciler) Run(ctx context.Context) error {
+	conn, err := grpc.NewClient(
+		fmt.Sprintf("unix://%s", r.socket),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSize)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create pod resources client: %w", err)
+	}
+	defer conn.Close() //nolint:errcheck // best effort call
+
+	client := podresourcesv1.NewPodResourcesListerClient(conn)
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		if err := r.Reconcile(ctx, client); err != nil {
+			r.log.Error("Failed to reconcile pod resources", "error", err)
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			err := ctx.Err()
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Reconcile lists pod resources once and replaces the tracked allocations.
+func (r *Reconciler) Reconcile(ctx context.Context, client podresourcesv1.PodResourcesListerClient) error {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	res, err := client.List(ctx, &podresourcesv1.ListPodResourcesRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to list pod resources: %w", err)
+	}
+
+	allocations := Allocations{}
+	for name := range r.resources {
+		allocations[name] = map[string]Owner{}
+	}
+
+	for _, pod := range res.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, devs := range container.GetDevices() {
+				ids, ok := allocations[devs.GetResourceName()]
+				if !ok {
+					continue
+				}
+				for _, id := range devs.GetDeviceIds() {
+					ids[id] = Owner{
+						Namespace: pod.GetNamespace(),
+						Pod:       pod.GetName(),
+						Container: container.GetName(),
+					}
+				}
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.allocations = allocations
+	subscribers := r.subscribers
+	r.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.UpdateAllocations(r.Allocations())
+	}
+
+	metrics.DeviceAllocations.Reset()
+	for resource, ids := range allocations {
+		for id, owner := range ids {
+			metrics.DeviceAllocations.WithLabelValues(resource, id, owner.Namespace, owner.Pod, owner.Container).Set(1)
+		}
+	}
+
+	return nil
+}
+
+// Allocations returns a copy of the tracked allocations.
+func (r *Reconciler) Allocations() Allocations {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	allocations := make(Allocations, len(r.allocations))
+	for resource, ids := range r.allocations {
+		allocations[resource] = maps.Clone(ids)
+	}
+	return allocations
+}
+
+// ServeHTTP responds with the tracked allocations encoded as JSON.
+func (r *Reconciler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.Allocations()); err != nil {
+		r.log.Error("Failed to encode allocations", "error", err)
+	}
+}
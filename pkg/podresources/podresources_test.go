@@ -0,0 +1,151 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podresources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// fakeClient returns the pod resources it holds from List.
+type fakeClient struct {
+	podresourcesv1.PodResourcesListerClient
+
+	pods []*podresourcesv1.PodResources
+}
+
+func (c *fakeClient) List(
+	context.Context,
+	*podresourcesv1.ListPodResourcesRequest,
+	...grpc.CallOption,
+) (*podresourcesv1.ListPodResourcesResponse, error) {
+	return &podresourcesv1.ListPodResourcesResponse{PodResources: c.pods}, nil
+}
+
+type subscriber struct {
+	allocations []Allocations
+}
+
+func (s *subscriber) UpdateAllocations(allocations Allocations) {
+	s.allocations = append(s.allocations, allocations)
+}
+
+func pod(namespace, name, container, resource string, ids ...string) *podresourcesv1.PodResources {
+	return &podresourcesv1.PodResources{
+		Namespace: namespace,
+		Name:      name,
+		Containers: []*podresourcesv1.ContainerResources{
+			{
+				Name: container,
+				Devices: []*podresourcesv1.ContainerDevices{
+					{ResourceName: resource, DeviceIds: ids},
+				},
+			},
+		},
+	}
+}
+
+// TestReconcile is not parallel, as it asserts the global allocation gauge.
+func TestReconcile(t *testing.T) {
+	client := &fakeClient{
+		pods: []*podresourcesv1.PodResources{
+			pod("default", "vm-0", "vmm", "example.com/kvm", "kvm0", "kvm1"),
+			pod("default", "gpu-0", "app", "example.com/gpu", "gpu0"),
+		},
+	}
+	s := &subscriber{}
+	r := New("", 0, []string{"example.com/kvm", "example.com/tun"}, nil)
+	r.Subscribe(s)
+
+	if err := r.Reconcile(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := Owner{Namespace: "default", Pod: "vm-0", Container: "vmm"}
+	want := Allocations{
+		"example.com/kvm": {"kvm0": owner, "kvm1": owner},
+		"example.com/tun": {},
+	}
+	if got := r.Allocations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Allocations() = %v, want %v", got, want)
+	}
+	if len(s.allocations) != 1 || !reflect.DeepEqual(s.allocations[0], want) {
+		t.Errorf("subscriber notified with %v, want %v", s.allocations, want)
+	}
+
+	if got := testutil.ToFloat64(metrics.DeviceAllocations.WithLabelValues(
+		"example.com/kvm", "kvm1", "default", "vm-0", "vmm")); got != 1 {
+		t.Errorf("device_allocation of kvm1 = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(metrics.DeviceAllocations); got != 2 {
+		t.Errorf("device_allocation has %d series, want 2", got)
+	}
+
+	// allocations of deleted pods are dropped
+	client.pods = nil
+	if err := r.Reconcile(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	want = Allocations{"example.com/kvm": {}, "example.com/tun": {}}
+	if got := r.Allocations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Allocations() = %v, want %v", got, want)
+	}
+	if len(s.allocations) != 2 || !reflect.DeepEqual(s.allocations[1], want) {
+		t.Errorf("subscriber notified with %v, want %v", s.allocations, want)
+	}
+	if got := testutil.CollectAndCount(metrics.DeviceAllocations); got != 0 {
+		t.Errorf("device_allocation has %d series, want 0", got)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	r := New("", 0, []string{"example.com/kvm"}, nil)
+	r.allocations = Allocations{
+		"example.com/kvm": {"kvm3": {Namespace: "default", Pod: "vm-0", Container: "vmm"}},
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/allocations", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var got map[string]map[string]map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]map[string]string{
+		"example.com/kvm": {"kvm3": {"namespace": "default", "pod": "vm-0", "container": "vmm"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("body = %s, want %v", rec.Body, want)
+	}
+}
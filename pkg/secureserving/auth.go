@@ -0,0 +1,209 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secureserving
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultCacheTTL is how long authentication and authorization decisions are cached.
+	DefaultCacheTTL = time.Minute
+
+	reviewTimeout = 10 * time.Second
+)
+
+// Authorizer authenticates bearer tokens using TokenReview and authorizes the requests
+// using SubjectAccessReview for non-resource URLs, in the same way as kube-rbac-proxy.
+// A client must be allowed to "get" the requested path, e.g.:
+//
+//	rules:
+//	  - nonResourceURLs: ["/metrics"]
+//	    verbs: ["get"]
+type Authorizer struct {
+	log    *slog.Logger
+	client kubernetes.Interface
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	decisions map[decisionKey]decision
+}
+
+type decisionKey struct {
+	token string
+	verb  string
+	path  string
+}
+
+type decision struct {
+	allowed bool
+	user    string
+	expires time.Time
+}
+
+func NewAuthorizer(client kubernetes.Interface, ttl time.Duration, log *slog.Logger) *Authorizer {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	return &Authorizer{
+		log:       log,
+		client:    client,
+		ttl:       ttl,
+		decisions: map[decisionKey]decision{},
+	}
+}
+
+// Wrap returns a handler that serves next only to authenticated and authorized requests.
+func (a *Authorizer) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := bearerToken(req)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		key := decisionKey{
+			token: token,
+			verb:  verb(req.Method),
+			path:  req.URL.Path,
+		}
+
+		d, err := a.decide(req.Context(), key)
+		switch {
+		case err != nil:
+			a.log.Error("Failed to authorize request", "path", key.path, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		case d.user == "":
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case !d.allowed:
+			a.log.Debug("Forbidden request", "user", d.user, "verb", key.verb, "path", key.path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		default:
+			next.ServeHTTP(w, req)
+		}
+	})
+}
+
+func (a *Authorizer) decide(ctx context.Context, key decisionKey) (decision, error) {
+	now := time.Now()
+
+	a.mu.Lock()
+	d, ok := a.decisions[key]
+	a.mu.Unlock()
+	if ok && now.Before(d.expires) {
+		return d, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reviewTimeout)
+	defer cancel()
+
+	d, err := a.review(ctx, key)
+	if err != nil {
+		return decision{}, err
+	}
+	d.expires = now.Add(a.ttl)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// drop expired decisions, so that the cache does not grow with rotated tokens
+	for k, v := range a.decisions {
+		if now.After(v.expires) {
+			delete(a.decisions, k)
+		}
+	}
+	a.decisions[key] = d
+
+	return d, nil
+}
+
+func (a *Authorizer) review(ctx context.Context, key decisionKey) (decision, error) {
+	tr, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: key.token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return decision{}, fmt.Errorf("token review failed: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return decision{}, nil
+	}
+
+	user := tr.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: key.path,
+				Verb: key.verb,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return decision{}, fmt.Errorf("subject access review failed: %w", err)
+	}
+
+	return decision{
+		allowed: sar.Status.Allowed,
+		user:    user.Username,
+	}, nil
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// verb maps HTTP methods to Kubernetes verbs of non-resource requests.
+func verb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secureserving
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeReviews answers TokenReviews and SubjectAccessReviews for the known tokens and paths.
+type fakeReviews struct {
+	tokens  map[string]string // token -> user
+	allowed map[string]string // user -> allowed path
+	err     error
+
+	tokenReviews  atomic.Int32
+	accessReviews atomic.Int32
+}
+
+func (f *fakeReviews) client() *fake.Clientset {
+	client := fake.NewClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		f.tokenReviews.Add(1)
+		if f.err != nil {
+			return true, nil, f.err
+		}
+
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		if user, ok := f.tokens[tr.Spec.Token]; ok {
+			tr.Status.Authenticated = true
+			tr.Status.User = authenticationv1.UserInfo{Username: user}
+		}
+		return true, tr, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		f.accessReviews.Add(1)
+
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		attrs := sar.Spec.NonResourceAttributes
+		sar.Status.Allowed = attrs != nil && attrs.Verb == "get" && f.allowed[sar.Spec.User] == attrs.Path
+		return true, sar, nil
+	})
+	return client
+}
+
+func serve(t *testing.T, h http.Handler, method, path, authorization string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for name, tc := range map[string]struct {
+		method        string
+		path          string
+		authorization string
+		err           error
+		want          int
+	}{
+		"allowed":          {http.MethodGet, "/metrics", "Bearer reader", nil, http.StatusOK},
+		"lowercase scheme": {http.MethodGet, "/metrics", "bearer reader", nil, http.StatusOK},
+		"no token":         {http.MethodGet, "/metrics", "", nil, http.StatusUnauthorized},
+		"basic auth":       {http.MethodGet, "/metrics", "Basic cmVhZGVyOg==", nil, http.StatusUnauthorized},
+		"unknown token":    {http.MethodGet, "/metrics", "Bearer unknown", nil, http.StatusUnauthorized},
+		"denied path":      {http.MethodGet, "/loglevel", "Bearer reader", nil, http.StatusForbidden},
+		"denied verb":      {http.MethodPut, "/metrics", "Bearer reader", nil, http.StatusForbidden},
+		"denied user":      {http.MethodGet, "/metrics", "Bearer other", nil, http.StatusForbidden},
+		"token review failure": {
+			http.MethodGet, "/metrics", "Bearer reader", errors.New("unavailable"), http.StatusInternalServerError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f := &fakeReviews{
+				tokens:  map[string]string{"reader": "system:serviceaccount:monitoring:prometheus", "other": "jane"},
+				allowed: map[string]string{"system:serviceaccount:monitoring:prometheus": "/metrics"},
+				err:     tc.err,
+			}
+			h := NewAuthorizer(f.client(), time.Minute, nil).Wrap(ok)
+
+			if got := serve(t, h, tc.method, tc.path, tc.authorization); got != tc.want {
+				t.Errorf("status = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizerCache(t *testing.T) {
+	t.Parallel()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	newReviews := func() *fakeReviews {
+		return &fakeReviews{
+			tokens:  map[string]string{"reader": "prometheus"},
+			allowed: map[string]string{"prometheus": "/metrics"},
+		}
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		t.Parallel()
+
+		f := newReviews()
+		h := NewAuthorizer(f.client(), time.Minute, nil).Wrap(ok)
+
+		for range 3 {
+			if got := serve(t, h, http.MethodGet, "/metrics", "Bearer reader"); got != http.StatusOK {
+				t.Fatalf("status = %d, want %d", got, http.StatusOK)
+			}
+		}
+		// denied decisions are cached as well
+		for range 2 {
+			if got := serve(t, h, http.MethodGet, "/other", "Bearer reader"); got != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d", got, http.StatusForbidden)
+			}
+		}
+
+		if got := f.tokenReviews.Load(); got != 2 {
+			t.Errorf("token reviews = %d, want 2", got)
+		}
+		if got := f.accessReviews.Load(); got != 2 {
+			t.Errorf("access reviews = %d, want 2", got)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+
+		f := newReviews()
+		h := NewAuthorizer(f.client(), time.Nanosecond, nil).Wrap(ok)
+
+		for range 2 {
+			if got := serve(t, h, http.MethodGet, "/metrics", "Bearer reader"); got != http.StatusOK {
+				t.Fatalf("status = %d, want %d", got, http.StatusOK)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if got := f.tokenReviews.Load(); got != 2 {
+			t.Errorf("token reviews = %d, want 2", got)
+		}
+	})
+}
+
+func TestVerb(t *testing.T) {
+	t.Parallel()
+
+	for method, want := range map[string]string{
+		http.MethodGet:    "get",
+		http.MethodHead:   "get",
+		http.MethodPost:   "create",
+		http.MethodPut:    "update",
+		http.MethodPatch:  "patch",
+		http.MethodDelete: "delete",
+	} {
+		if got := verb(method); got != want {
+			t.Errorf("verb(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drideviceplugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	driDir  = "/dev/dri"
+	driName = "dri"
+	rwPerm  = "rw"
+
+	driMajor = 226
+)
+
+// DefaultHealthChecker verifies that the device node is the DRM render node its name
+// refers to and that it can be opened.
+var DefaultHealthChecker = healthcheck.CheckerFunc(func(path string) error {
+	n, err := minor(filepath.Base(path))
+	if err != nil {
+		return &healthcheck.Error{Reason: healthcheck.ReasonProbeFailed, Err: err}
+	}
+	return healthcheck.All(
+		healthcheck.CharDevice(driMajor, n),
+		healthcheck.Open(unix.O_RDWR),
+	).Check(path)
+})
+
+// ResourceName returns the name of the resource of render nodes of GPUs bound to the
+// driver, e.g. dri-virtio-gpu for virtio_gpu.
+func ResourceName(driver string) string {
+	return driName + "-" + strings.ReplaceAll(driver, "_", "-")
+}
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	namespace string
+	driver    string
+	replicas  uint
+	sysfsRoot string
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+
+	mu    sync.RWMutex
+	nodes map[string]string
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// Servers creates a server for every driver in drivers, and for the driver of every GPU
+// with a render node in the sysfs tree at sysfsRoot.
+func Servers(
+	namespace string,
+	sysfsRoot string,
+	drivers []string,
+	replicas uint,
+	cdiConfig cdi.Config,
+	log *slog.Logger,
+) ([]*Server, error) {
+	nodes, err := scan(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]struct{}{}
+	for _, d := range drivers {
+		names[d] = struct{}{}
+	}
+	for _, node := range nodes {
+		names[node.driver] = struct{}{}
+	}
+
+	servers := make([]*Server, 0, len(names))
+	for _, d := range slices.Sorted(maps.Keys(names)) {
+		servers = append(servers, New(namespace, d, replicas, sysfsRoot, cdiConfig, nil, log))
+	}
+	return servers, nil
+}
+
+// New creates the DRI device plugin server of render nodes of GPUs bound to the driver.
+// Every render node is advertised as replicas devices, shared by as many containers. If
+// checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	driver string,
+	replicas uint,
+	sysfsRoot string,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	if sysfsRoot == "" {
+		sysfsRoot = DefaultSysfsRoot
+	}
+	if replicas == 0 {
+		replicas = 1
+	}
+	s := &Server{
+		log:       log.With("driver", driver),
+		namespace: namespace,
+		driver:    driver,
+		replicas:  replicas,
+		sysfsRoot: sysfsRoot,
+		cdi:       cdiConfig,
+		checker:   checker,
+		nodes:     map[string]string{},
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		s.log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		s.log.Warn("No render node found")
+	}
+	return s
+}
+
+// Discover lists the render nodes of GPUs bound to the driver and rebuilds the list of
+// advertised devices. Changes are published to kubelet on the next call to Update.
+func (s *Server) Discover() error {
+	list, err := scan(s.sysfsRoot)
+	if err != nil {
+		return err
+	}
+
+	devs := []*v1beta1.Device{}
+	nodes := map[string]string{}
+	for _, node := range list {
+		if node.driver != s.driver {
+			continue
+		}
+
+		health := v1beta1.Healthy
+		if herr := s.checker.Check(nodePath(node.name)); herr != nil {
+			health = v1beta1.Unhealthy
+			if !s.wasUnhealthy(node.name) {
+				reason := healthcheck.Reason(herr)
+				s.log.Warn("Render node is unhealthy", "node", node.name, "reason", reason, "error", herr)
+				metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+			}
+		}
+
+		var topology *v1beta1.TopologyInfo
+		if node.numa >= 0 {
+			topology = &v1beta1.TopologyInfo{
+				Nodes: []*v1beta1.NUMANode{{ID: node.numa}},
+			}
+		}
+
+		for i := uint(0); i < s.replicas; i++ {
+			id := s.deviceID(node.name, i)
+			devs = append(devs, &v1beta1.Device{
+				ID:       id,
+				Health:   health,
+				Topology: topology,
+			})
+			nodes[id] = node.name
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plugin.EqualDevices(devs, s.Devices()) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("Render nodes disappeared")
+	case len(devs) != len(s.Devices()):
+		s.log.Info("Discovered render nodes", "nodes", len(devs)/int(s.replicas), "devices", len(devs))
+	}
+
+	s.nodes = nodes
+	s.SetDevices(devs)
+	return nil
+}
+
+// wasUnhealthy reports whether the render node was advertised as unhealthy before.
+func (s *Server) wasUnhealthy(node string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devs := s.Devices()
+	i := slices.IndexFunc(devs, func(dev *v1beta1.Device) bool { return s.nodes[dev.ID] == node })
+	return i >= 0 && devs[i].Health == v1beta1.Unhealthy
+}
+
+// deviceID returns the ID of the i-th replica of the render node. Render nodes which are
+// not shared are advertised by their name.
+func (s *Server) deviceID(node string, i uint) string {
+	if s.replicas == 1 {
+		return node
+	}
+	return fmt.Sprintf("%s-%d", node, i)
+}
+
+func nodePath(node string) string {
+	return path.Join(driDir, node)
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		p := nodePath(s.nodes[dev.ID])
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name: dev.ID,
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        p,
+						HostPath:    p,
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the render node patterns watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	return []string{path.Join(driDir, renderPrefix+"*")}
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, ResourceName(s.driver))
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, ResourceName(s.driver)+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		// replicas of the same render node inject the same device node, so that only the
+		// first replica of every render node is passed to the container
+		nodes := make([]string, 0, len(creq.DevicesIDs))
+		ids := make([]string, 0, len(creq.DevicesIDs))
+		for _, id := range creq.DevicesIDs {
+			node, ok := s.nodes[id]
+			if !ok {
+				return nil, status.Errorf(codes.NotFound, "unknown render node %q", id)
+			}
+			if slices.Contains(nodes, node) {
+				continue
+			}
+			nodes = append(nodes, node)
+			ids = append(ids, id)
+		}
+
+		cres := &v1beta1.ContainerAllocateResponse{}
+		if s.cdi.Mode.DeviceSpec() {
+			for _, node := range nodes {
+				cres.Devices = append(cres.Devices, &v1beta1.DeviceSpec{
+					ContainerPath: nodePath(node),
+					HostPath:      nodePath(node),
+					Permissions:   rwPerm,
+				})
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), ids)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
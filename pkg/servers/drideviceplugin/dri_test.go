@@ -0,0 +1,106 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drideviceplugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthy accepts any render node, as device nodes can not be created in tests.
+var healthy = healthcheck.CheckerFunc(func(string) error { return nil })
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	s := New("devices.anza-labs.dev", "virtio_gpu", 2, fakeSysfs(t),
+		cdi.Config{Mode: cdi.ModeDeviceSpec}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	devs := s.Advertised()
+	ids := make([]string, 0, len(devs))
+	for _, dev := range devs {
+		ids = append(ids, dev.ID)
+		if dev.Topology == nil || len(dev.Topology.Nodes) != 1 || dev.Topology.Nodes[0].ID != 1 {
+			t.Errorf("topology of %s = %v, want NUMA node 1", dev.ID, dev.Topology)
+		}
+	}
+	if want := []string{"renderD128-0", "renderD128-1"}; !slices.Equal(ids, want) {
+		t.Errorf("advertised %v, want %v", ids, want)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	s := New("devices.anza-labs.dev", "virtio_gpu", 3, fakeSysfs(t),
+		cdi.Config{Mode: cdi.ModeBoth, SpecDir: t.TempDir()}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"renderD128-2", "renderD128-0"}},
+			{DevicesIDs: []string{"renderD128-1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replicas of the same render node are passed to a container once
+	for i, want := range []string{"renderD128-2", "renderD128-1"} {
+		cres := res.ContainerResponses[i]
+		if len(cres.Devices) != 1 || cres.Devices[0].HostPath != "/dev/dri/renderD128" {
+			t.Errorf("Devices of container %d = %v, want /dev/dri/renderD128", i, cres.Devices)
+		}
+		name := cdi.QualifiedName(s.Name(), want)
+		if len(cres.CDIDevices) != 1 || cres.CDIDevices[0].Name != name {
+			t.Errorf("CDI devices of container %d = %v, want %s", i, cres.CDIDevices, name)
+		}
+	}
+}
+
+func TestServers(t *testing.T) {
+	t.Parallel()
+
+	servers, err := Servers("devices.anza-labs.dev", fakeSysfs(t), []string{"i915"}, 1, cdi.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, s := range servers {
+			s.Close() //nolint:errcheck // best effort call
+		}
+	})
+
+	names := make([]string, 0, len(servers))
+	for _, s := range servers {
+		names = append(names, s.Name())
+	}
+	want := []string{
+		"devices.anza-labs.dev/dri-amdgpu",
+		"devices.anza-labs.dev/dri-i915",
+		"devices.anza-labs.dev/dri-virtio-gpu",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("servers = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drideviceplugin
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSysfsRoot is the mount point of sysfs on the host.
+	DefaultSysfsRoot = "/sys"
+
+	renderPrefix = "renderD"
+)
+
+// renderNode is a DRM render node, e.g. /dev/dri/renderD128.
+type renderNode struct {
+	name   string
+	driver string
+	// numa is the NUMA node of the PCI device of the GPU, or -1 if unknown.
+	numa int64
+}
+
+// scan lists the render nodes of all GPUs in the sysfs tree, sorted by minor number.
+func scan(root string) ([]renderNode, error) {
+	dir := filepath.Join(root, "class", "drm")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil // no GPUs
+		}
+		return nil, fmt.Errorf("failed to list DRM devices: %w", err)
+	}
+
+	nodes := []renderNode{}
+	for _, e := range entries {
+		if _, err := minor(e.Name()); err != nil {
+			continue // e.g. card0 or card0-HDMI-A-1
+		}
+
+		dev, err := filepath.EvalSymlinks(filepath.Join(dir, e.Name(), "device"))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // removed in the meantime
+			}
+			return nil, fmt.Errorf("failed to resolve device of %s: %w", e.Name(), err)
+		}
+
+		driver, err := os.Readlink(filepath.Join(dev, "driver"))
+		if err != nil {
+			continue // not bound to a driver (anymore)
+		}
+
+		nodes = append(nodes, renderNode{
+			name:   e.Name(),
+			driver: filepath.Base(driver),
+			numa:   numaNode(root, dev),
+		})
+	}
+	slices.SortFunc(nodes, func(a, b renderNode) int {
+		x, _ := minor(a.name)
+		y, _ := minor(b.name)
+		return cmp.Compare(x, y)
+	})
+
+	return nodes, nil
+}
+
+// minor returns the minor number of the render node, e.g. 128 for renderD128.
+func minor(name string) (uint32, error) {
+	n, ok := strings.CutPrefix(name, renderPrefix)
+	if !ok {
+		return 0, fmt.Errorf("%s is not a render node", name)
+	}
+	v, err := strconv.ParseUint(n, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a render node: %w", name, err)
+	}
+	return uint32(v), nil
+}
+
+// numaNode returns the NUMA node of the PCI device the GPU belongs to. The GPU device may
+// be the PCI device itself, or a child of it, e.g. a virtio device.
+func numaNode(root, dev string) int64 {
+	for root = filepath.Clean(root); dev != root && dev != filepath.Dir(dev); dev = filepath.Dir(dev) {
+		data, err := os.ReadFile(filepath.Join(dev, "numa_node"))
+		if err != nil {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drideviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSysfs creates a sysfs tree with the GPUs of a virtual machine:
+//   - renderD128 of a virtio GPU, a child of a PCI device on NUMA node 1
+//   - renderD129 of an amdgpu PCI device without NUMA information
+//   - renderD1000 of an unbound PCI device
+//   - card0, which is not a render node
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	mkdir := func(dir string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(file, data string) {
+		t.Helper()
+		mkdir(filepath.Dir(file))
+		if err := os.WriteFile(file, []byte(data+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink := func(target, link string) {
+		t.Helper()
+		mkdir(filepath.Dir(link))
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pci := filepath.Join(root, "devices", "pci0000:00")
+
+	virtio := filepath.Join(pci, "0000:00:02.0", "virtio0")
+	write(filepath.Join(pci, "0000:00:02.0", "numa_node"), "1")
+	symlink(filepath.Join(root, "bus", "virtio", "drivers", "virtio_gpu"), filepath.Join(virtio, "driver"))
+	symlink(virtio, filepath.Join(root, "class", "drm", "renderD128", "device"))
+	symlink(virtio, filepath.Join(root, "class", "drm", "card0", "device"))
+
+	amdgpu := filepath.Join(pci, "0000:00:03.0")
+	write(filepath.Join(amdgpu, "numa_node"), "-1")
+	symlink(filepath.Join(root, "bus", "pci", "drivers", "amdgpu"), filepath.Join(amdgpu, "driver"))
+	symlink(amdgpu, filepath.Join(root, "class", "drm", "renderD129", "device"))
+
+	unbound := filepath.Join(pci, "0000:00:04.0")
+	mkdir(unbound)
+	symlink(unbound, filepath.Join(root, "class", "drm", "renderD1000", "device"))
+
+	return root
+}
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	nodes, err := scan(fakeSysfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []renderNode{
+		{name: "renderD128", driver: "virtio_gpu", numa: 1},
+		{name: "renderD129", driver: "amdgpu", numa: -1},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("scan() = %+v, want %+v", nodes, want)
+	}
+	for i := range want {
+		if nodes[i] != want[i] {
+			t.Errorf("scan()[%d] = %+v, want %+v", i, nodes[i], want[i])
+		}
+	}
+}
+
+func TestScanWithoutGPUs(t *testing.T) {
+	t.Parallel()
+
+	nodes, err := scan(t.TempDir())
+	if err != nil || nodes != nil {
+		t.Errorf("scan() = %v, %v, want no render nodes", nodes, err)
+	}
+}
+
+func TestMinor(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]int64{
+		"renderD128":   128,
+		"renderD1000":  1000,
+		"card0":        -1,
+		"renderD":      -1,
+		"renderD128x":  -1,
+		"renderD-1":    -1,
+		"controlD64":   -1,
+		"renderD99999": 99999,
+	} {
+		got, err := minor(name)
+		switch {
+		case want < 0 && err == nil:
+			t.Errorf("minor(%q) = %d, want error", name, got)
+		case want >= 0 && (err != nil || int64(got) != want):
+			t.Errorf("minor(%q) = %d, %v, want %d", name, got, err, want)
+		}
+	}
+}
+
+func TestNUMANode(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	pci := filepath.Join(root, "devices", "pci0000:00", "0000:00:02.0")
+	child := filepath.Join(pci, "virtio0")
+	if err := os.MkdirAll(child, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// no numa_node up to the sysfs root
+	if got := numaNode(root, child); got != -1 {
+		t.Errorf("numaNode() = %d without numa_node, want -1", got)
+	}
+
+	for data, want := range map[string]int64{"0\n": 0, "3\n": 3, "-1\n": -1, "invalid\n": -1} {
+		if err := os.WriteFile(filepath.Join(pci, "numa_node"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// the NUMA node of the parent PCI device is used for its children
+		if got := numaNode(root, child); got != want {
+			t.Errorf("numaNode() = %d for %q, want %d", got, data, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fusedeviceplugin
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	fusePath = "/dev/fuse"
+	fuseName = "fuse"
+
+	fuseMajor = 10
+	fuseMinor = 229
+
+	// propagationAnnotation is the name, within the plugin namespace, of the annotation
+	// carrying the mount propagation hint.
+	propagationAnnotation = "mount-propagation"
+)
+
+// DefaultHealthChecker verifies that /dev/fuse is the FUSE misc device and that it can
+// be opened.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.CharDevice(fuseMajor, fuseMinor),
+	healthcheck.Open(unix.O_RDWR),
+)
+
+// Propagation is the mount propagation hinted to runtimes for containers using FUSE, so
+// that filesystems mounted in the container become visible to other containers.
+type Propagation string
+
+const (
+	// PropagationNone disables the hint.
+	PropagationNone Propagation = ""
+	// PropagationHostToContainer hints rslave propagation of the container mounts.
+	PropagationHostToContainer Propagation = "HostToContainer"
+	// PropagationBidirectional hints rshared propagation of the container mounts.
+	PropagationBidirectional Propagation = "Bidirectional"
+)
+
+// ParsePropagation parses the value of the --mount-propagation flag.
+func ParsePropagation(s string) (Propagation, error) {
+	switch p := Propagation(s); p {
+	case PropagationNone, PropagationHostToContainer, PropagationBidirectional:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown mount propagation %q, expected one of: %s, %s",
+			s, PropagationHostToContainer, PropagationBidirectional)
+	}
+}
+
+type Server struct {
+	*plugin.NodeServer
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the FUSE device plugin server. Unless propagation is PropagationNone, it is
+// passed to runtimes as annotation of the allocated containers and CDI devices. If
+// checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	devices uint,
+	propagation Propagation,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	return newServer(fusePath, namespace, devices, propagation, cdiConfig, checker, log)
+}
+
+func newServer(
+	devPath string,
+	namespace string,
+	devices uint,
+	propagation Propagation,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	return &Server{
+		NodeServer: plugin.NewNodeServer(plugin.NodeConfig{
+			Namespace:   namespace,
+			Name:        fuseName,
+			Kind:        "FUSE",
+			Path:        devPath,
+			Devices:     devices,
+			Checker:     checker,
+			Annotations: annotations(namespace, propagation),
+		}, cdiConfig, log),
+	}
+}
+
+// annotations returns the mount propagation hint, or nil if it is disabled.
+func annotations(namespace string, propagation Propagation) map[string]string {
+	if propagation == PropagationNone {
+		return nil
+	}
+	return map[string]string{
+		path.Join(namespace, propagationAnnotation): string(propagation),
+	}
+}
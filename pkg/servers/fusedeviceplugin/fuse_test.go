@@ -0,0 +1,123 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fusedeviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthy accepts any device node, as device nodes can not be created in tests.
+var healthy = healthcheck.CheckerFunc(func(string) error { return nil })
+
+func TestParsePropagation(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  Propagation
+		err   string
+	}{
+		{name: "empty", value: "", want: PropagationNone},
+		{name: "host to container", value: "HostToContainer", want: PropagationHostToContainer},
+		{name: "bidirectional", value: "Bidirectional", want: PropagationBidirectional},
+		{name: "wrong case", value: "bidirectional", err: "unknown mount propagation"},
+		{name: "invalid", value: "None", err: "unknown mount propagation"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePropagation(tc.value)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			}
+			if got != tc.want {
+				t.Errorf("ParsePropagation(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	const annotation = "devices.anza-labs.dev/mount-propagation"
+
+	for _, tc := range []struct {
+		name        string
+		propagation Propagation
+	}{
+		{name: "none", propagation: PropagationNone},
+		{name: "host to container", propagation: PropagationHostToContainer},
+		{name: "bidirectional", propagation: PropagationBidirectional},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			devPath := filepath.Join(t.TempDir(), "fuse")
+			if err := os.WriteFile(devPath, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			specDir := t.TempDir()
+			s := newServer(devPath, "devices.anza-labs.dev", 1, tc.propagation,
+				cdi.Config{Mode: cdi.ModeBoth, SpecDir: specDir}, healthy, nil)
+			t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+			res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+				ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+					{DevicesIDs: []string{"fuse0"}},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := res.ContainerResponses[0].Annotations[annotation]
+			if ok != (tc.propagation != PropagationNone) || got != string(tc.propagation) {
+				t.Errorf("response annotation = %q (set: %v), want %q", got, ok, tc.propagation)
+			}
+
+			data, err := os.ReadFile(filepath.Join(specDir, "devices.anza-labs.dev-fuse.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var spec cdi.Spec
+			if err := yaml.Unmarshal(data, &spec); err != nil {
+				t.Fatal(err)
+			}
+			if len(spec.Devices) != 1 {
+				t.Fatalf("CDI spec devices = %v, want fuse0", spec.Devices)
+			}
+			got, ok = spec.Devices[0].Annotations[annotation]
+			if ok != (tc.propagation != PropagationNone) || got != string(tc.propagation) {
+				t.Errorf("CDI device annotation = %q (set: %v), want %q", got, ok, tc.propagation)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genericdeviceplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const defaultPermissions = "rw"
+
+// HealthCheck selects how the device nodes of a group are checked.
+type HealthCheck string
+
+const (
+	// HealthCheckNode verifies that matched paths are still device nodes.
+	HealthCheckNode HealthCheck = "node"
+	// HealthCheckOpen additionally opens the device nodes with their permissions. Opening
+	// some devices has side effects, e.g. serial ports toggle their modem control lines.
+	HealthCheckOpen HealthCheck = "open"
+	// HealthCheckNone disables health checks.
+	HealthCheckNone HealthCheck = "none"
+)
+
+var resourceNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// Config is the configuration file of the generic device plugin.
+type Config struct {
+	Groups []Group `json:"groups"`
+}
+
+// Group describes a single resource served by the generic device plugin.
+type Group struct {
+	// Name of the resource, advertised to kubelet as <namespace>/<name>.
+	Name string `json:"name"`
+	// Paths lists the device nodes belonging to the group.
+	Paths []Path `json:"paths"`
+	// Count is the number of devices advertised when all matched device nodes are
+	// shared by every container. Defaults to 1. Ignored if PerMatch is set.
+	Count uint `json:"count,omitempty"`
+	// PerMatch advertises every matched device node as a separate device, allocated
+	// exclusively to a single container.
+	PerMatch bool `json:"perMatch,omitempty"`
+	// HealthCheck selects how the device nodes are checked, defaults to HealthCheckNode.
+	// Devices with a failed check are advertised as unhealthy.
+	HealthCheck HealthCheck `json:"healthCheck,omitempty"`
+}
+
+// Path describes device nodes matched by a glob pattern.
+type Path struct {
+	// Path is a glob pattern matching device nodes below /dev on the host, e.g. /dev/ttyUSB*.
+	Path string `json:"path"`
+	// ContainerPath is the path of the device node in the container, defaults to the
+	// host path. If Path is a pattern, ContainerPath is the directory the matched
+	// device nodes are placed in.
+	ContainerPath string `json:"containerPath,omitempty"`
+	// Permissions are the cgroup permissions of the device nodes, defaults to "rw".
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// LoadConfig reads, defaults and validates the configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	cfg.Default()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Default sets default values of optional fields.
+func (c *Config) Default() {
+	for i := range c.Groups {
+		g := &c.Groups[i]
+		if g.Count == 0 {
+			g.Count = 1
+		}
+		if g.HealthCheck == "" {
+			g.HealthCheck = HealthCheckNode
+		}
+		for j := range g.Paths {
+			if g.Paths[j].Permissions == "" {
+				g.Paths[j].Permissions = defaultPermissions
+			}
+		}
+	}
+}
+
+// Validate checks that the configuration describes a valid set of resources.
+func (c *Config) Validate() error {
+	if len(c.Groups) == 0 {
+		return errors.New("no groups configured")
+	}
+
+	names := map[string]struct{}{}
+	for _, g := range c.Groups {
+		if !resourceNameRegexp.MatchString(g.Name) {
+			return fmt.Errorf("invalid group name %q", g.Name)
+		}
+		if _, ok := names[g.Name]; ok {
+			return fmt.Errorf("duplicate group name %q", g.Name)
+		}
+		names[g.Name] = struct{}{}
+
+		if len(g.Paths) == 0 {
+			return fmt.Errorf("group %q has no paths", g.Name)
+		}
+		switch g.HealthCheck {
+		case "", HealthCheckNode, HealthCheckOpen, HealthCheckNone:
+		default:
+			return fmt.Errorf("group %q: unknown health check %q, expected one of: %s, %s, %s",
+				g.Name, g.HealthCheck, HealthCheckNode, HealthCheckOpen, HealthCheckNone)
+		}
+		for _, p := range g.Paths {
+			if !strings.HasPrefix(p.Path, "/") {
+				return fmt.Errorf("group %q: path %q is not absolute", g.Name, p.Path)
+			}
+			// device IDs are derived from the path below /dev, see deviceID
+			if p.Path != filepath.Clean(p.Path) || !strings.HasPrefix(p.Path, "/dev/") {
+				return fmt.Errorf("group %q: path %q is not a clean path below /dev", g.Name, p.Path)
+			}
+			if _, err := filepath.Match(p.Path, ""); err != nil {
+				return fmt.Errorf("group %q: invalid path %q: %w", g.Name, p.Path, err)
+			}
+			if p.ContainerPath != "" && !strings.HasPrefix(p.ContainerPath, "/") {
+				return fmt.Errorf("group %q: container path %q is not absolute", g.Name, p.ContainerPath)
+			}
+			if strings.Trim(p.Permissions, "rwm") != "" {
+				return fmt.Errorf("group %q: invalid permissions %q", g.Name, p.Permissions)
+			}
+		}
+	}
+
+	return nil
+}
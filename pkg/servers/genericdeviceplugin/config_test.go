@@ -0,0 +1,102 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genericdeviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "defaults",
+			config: "groups:\n- name: fuse\n  paths:\n  - path: /dev/fuse\n",
+		},
+		{
+			name:   "health check",
+			config: "groups:\n- name: tty\n  perMatch: true\n  healthCheck: open\n  paths:\n  - path: /dev/ttyUSB*\n",
+		},
+		{
+			name:   "unknown health check",
+			config: "groups:\n- name: tty\n  healthCheck: ping\n  paths:\n  - path: /dev/ttyUSB*\n",
+			err:    "unknown health check",
+		},
+		{
+			name:   "duplicate group",
+			config: "groups:\n- name: a\n  paths:\n  - path: /dev/a\n- name: a\n  paths:\n  - path: /dev/b\n",
+			err:    "duplicate group name",
+		},
+		{
+			name:   "relative path",
+			config: "groups:\n- name: a\n  paths:\n  - path: dev/a\n",
+			err:    "not absolute",
+		},
+		{
+			name:   "outside of dev",
+			config: "groups:\n- name: a\n  paths:\n  - path: /sys/a\n",
+			err:    "not a clean path below /dev",
+		},
+		{
+			name:   "escaping dev",
+			config: "groups:\n- name: a\n  paths:\n  - path: /dev/../sys/a\n",
+			err:    "not a clean path below /dev",
+		},
+		{
+			name:   "unclean path",
+			config: "groups:\n- name: a\n  paths:\n  - path: /dev//a\n",
+			err:    "not a clean path below /dev",
+		},
+		{
+			name:   "unknown field",
+			config: "groups:\n- name: a\n  path: /dev/a\n",
+			err:    "failed to parse config",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tc.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig(file)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("LoadConfig() = %v, want error containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() = %v", err)
+			}
+
+			for _, g := range cfg.Groups {
+				if g.Count != 1 || g.HealthCheck == "" || g.Paths[0].Permissions != defaultPermissions {
+					t.Errorf("group %q is not defaulted: %+v", g.Name, g)
+				}
+			}
+		})
+	}
+}
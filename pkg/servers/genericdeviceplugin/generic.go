@@ -0,0 +1,376 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genericdeviceplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	namespace string
+	group     Group
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+
+	mu    sync.RWMutex
+	specs map[string][]*v1beta1.DeviceSpec
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the server of a group. If checker is nil, device nodes are checked as
+// selected by the HealthCheck of the group.
+func New(
+	namespace string,
+	group Group,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	s := &Server{
+		log:       log.With("group", group.Name),
+		namespace: namespace,
+		group:     group,
+		cdi:       cdiConfig,
+		checker:   checker,
+		specs:     map[string][]*v1beta1.DeviceSpec{},
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		s.log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		s.log.Warn("No devices found")
+	}
+	return s
+}
+
+// Discover matches the group paths against device nodes on the host, checks their
+// health and rebuilds the list of advertised devices. Changes are published to kubelet
+// on the next call to Update.
+func (s *Server) Discover() error {
+	var (
+		matched [][]*v1beta1.DeviceSpec
+		specs   = map[string][]*v1beta1.DeviceSpec{}
+	)
+
+	for _, p := range s.group.Paths {
+		nodes, err := match(p)
+		if err != nil {
+			return err
+		}
+		matched = append(matched, nodes)
+	}
+
+	if s.group.PerMatch {
+		for _, nodes := range matched {
+			for _, node := range nodes {
+				specs[deviceID(node.HostPath)] = []*v1beta1.DeviceSpec{node}
+			}
+		}
+	} else if !slices.ContainsFunc(matched, func(nodes []*v1beta1.DeviceSpec) bool { return len(nodes) == 0 }) {
+		// shared devices are only advertised if all paths of the group are present
+		all := slices.Concat(matched...)
+		for i := uint(0); i < s.group.Count; i++ {
+			specs[fmt.Sprintf("%s%d", s.group.Name, i)] = all
+		}
+	}
+
+	devs := make([]*v1beta1.Device, 0, len(specs))
+	unhealthy := 0
+	for _, id := range slices.Sorted(maps.Keys(specs)) {
+		health := v1beta1.Healthy
+		if herr := s.check(specs[id]); herr != nil {
+			health = v1beta1.Unhealthy
+			unhealthy++
+			if !s.wasUnhealthy(id) {
+				reason := healthcheck.Reason(herr)
+				s.log.Warn("Device is unhealthy", "device", id, "reason", reason, "error", herr)
+				metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+			}
+		}
+		devs = append(devs, &v1beta1.Device{
+			ID:     id,
+			Health: health,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plugin.EqualDevices(devs, s.Devices()) && maps.EqualFunc(specs, s.specs, equalSpecs) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("Devices disappeared")
+	case len(devs) != len(s.Devices()):
+		s.log.Info("Discovered devices", "devices", len(devs), "unhealthy", unhealthy)
+	}
+
+	s.specs = specs
+	s.SetDevices(devs)
+	return nil
+}
+
+// check runs the health checker against all device nodes of a device.
+func (s *Server) check(nodes []*v1beta1.DeviceSpec) error {
+	for _, node := range nodes {
+		checker := s.checker
+		if checker == nil {
+			checker = nodeChecker(s.group.HealthCheck, node.Permissions)
+		}
+		if err := checker.Check(node.HostPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeChecker returns the Checker of a device node with the permissions. Device nodes are
+// opened without blocking, with the access mode matching the permissions.
+func nodeChecker(hc HealthCheck, permissions string) healthcheck.Checker {
+	switch hc {
+	case HealthCheckNone:
+		return healthcheck.CheckerFunc(func(string) error { return nil })
+	case HealthCheckOpen:
+		return healthcheck.All(
+			healthcheck.AnyDevice(),
+			healthcheck.Open(openFlags(permissions)|unix.O_NONBLOCK|unix.O_NOCTTY),
+		)
+	default:
+		return healthcheck.AnyDevice()
+	}
+}
+
+func openFlags(permissions string) int {
+	r, w := strings.Contains(permissions, "r"), strings.Contains(permissions, "w")
+	switch {
+	case r && w:
+		return unix.O_RDWR
+	case w:
+		return unix.O_WRONLY
+	default:
+		return unix.O_RDONLY
+	}
+}
+
+// wasUnhealthy reports whether the device was advertised as unhealthy before.
+func (s *Server) wasUnhealthy(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devs := s.Devices()
+	i := slices.IndexFunc(devs, func(dev *v1beta1.Device) bool { return dev.ID == id })
+	return i >= 0 && devs[i].Health == v1beta1.Unhealthy
+}
+
+// match returns device specs of all device nodes matching the path pattern.
+func match(p Path) ([]*v1beta1.DeviceSpec, error) {
+	matches, err := filepath.Glob(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", p.Path, err)
+	}
+
+	nodes := []*v1beta1.DeviceSpec{}
+	for _, m := range matches {
+		fi, err := os.Stat(m)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // removed in the meantime
+			}
+			return nil, fmt.Errorf("failed to stat %s: %w", m, err)
+		}
+		if fi.Mode()&fs.ModeDevice == 0 {
+			continue
+		}
+
+		containerPath := m
+		switch {
+		case p.ContainerPath == "":
+		case isPattern(p.Path):
+			containerPath = path.Join(p.ContainerPath, filepath.Base(m))
+		default:
+			containerPath = p.ContainerPath
+		}
+
+		nodes = append(nodes, &v1beta1.DeviceSpec{
+			ContainerPath: containerPath,
+			HostPath:      m,
+			Permissions:   p.Permissions,
+		})
+	}
+
+	return nodes, nil
+}
+
+func equalSpecs(a, b []*v1beta1.DeviceSpec) bool {
+	return slices.EqualFunc(a, b, func(x, y *v1beta1.DeviceSpec) bool {
+		return x.HostPath == y.HostPath &&
+			x.ContainerPath == y.ContainerPath &&
+			x.Permissions == y.Permissions
+	})
+}
+
+func isPattern(p string) bool {
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+// deviceID derives a device ID from the host path, e.g. /dev/bus/usb/001 becomes bus-usb-001.
+// Slashes become dashes, while dashes, underscores and characters not allowed in CDI
+// device names are escaped as _xx, so that distinct paths never share an ID: /dev/a-b
+// becomes a_2db. Paths are clean and below /dev, as enforced by Config.Validate, so that
+// IDs never start with a dash, which is not allowed in CDI device names.
+func deviceID(hostPath string) string {
+	var b strings.Builder
+	for _, c := range []byte(strings.TrimPrefix(hostPath, "/dev/")) {
+		switch {
+		case c == '/':
+			b.WriteByte('-')
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String()
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		nodes := []*cdi.DeviceNode{}
+		for _, node := range s.specs[dev.ID] {
+			nodes = append(nodes, &cdi.DeviceNode{
+				Path:        node.ContainerPath,
+				HostPath:    node.HostPath,
+				Permissions: node.Permissions,
+			})
+		}
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name:           dev.ID,
+			ContainerEdits: cdi.ContainerEdits{DeviceNodes: nodes},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the device node patterns watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	paths := make([]string, 0, len(s.group.Paths))
+	for _, p := range s.group.Paths {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, s.group.Name)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, "generic-"+s.group.Name+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		for _, id := range creq.DevicesIDs {
+			if len(s.specs[id]) == 0 {
+				// the device disappeared since it was last published
+				s.log.Error("Rejecting allocation", "devices", creq.DevicesIDs, "device", id)
+				return nil, status.Errorf(codes.NotFound, "no device nodes of device %q", id)
+			}
+		}
+
+		cres := &v1beta1.ContainerAllocateResponse{}
+		if s.cdi.Mode.DeviceSpec() {
+			seen := map[string]struct{}{}
+			for _, id := range creq.DevicesIDs {
+				for _, node := range s.specs[id] {
+					// shared devices of the group inject the same device nodes
+					if _, ok := seen[node.ContainerPath]; ok {
+						continue
+					}
+					seen[node.ContainerPath] = struct{}{}
+					cres.Devices = append(cres.Devices, node)
+				}
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
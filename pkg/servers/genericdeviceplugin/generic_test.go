@@ -0,0 +1,185 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genericdeviceplugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func TestDeviceID(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path: "/dev/ttyUSB0", want: "ttyUSB0"},
+		{path: "/dev/bus/usb/001", want: "bus-usb-001"},
+		{path: "/dev/a-b", want: "a_2db"},
+		{path: "/dev/a/b", want: "a-b"},
+		{path: "/dev/a_b", want: "a_5fb"},
+		{path: "/dev/v4l/by-id/usb-cam:0", want: "v4l-by_2did-usb_2dcam_3a0"},
+		{path: "/dev/net/tun", want: "net-tun"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			if got := deviceID(tc.path); got != tc.want {
+				t.Errorf("deviceID(%q) = %q, want %q", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeviceIDCollisions(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{
+		"/dev/a-b", "/dev/a/b", "/dev/a_b", "/dev/a_2db", "/dev/a-/b", "/dev/a/-b",
+		"/dev/a--b", "/dev/a//b", "/dev/a_/b", "/dev/a/_b", "/dev/a_2d/b",
+	}
+	seen := map[string]string{}
+	for _, p := range paths {
+		id := deviceID(p)
+		if other, ok := seen[id]; ok {
+			t.Errorf("deviceID(%q) = deviceID(%q) = %q", p, other, id)
+		}
+		seen[id] = p
+	}
+}
+
+func TestOpenFlags(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		permissions string
+		want        int
+	}{
+		{permissions: "rw", want: unix.O_RDWR},
+		{permissions: "rwm", want: unix.O_RDWR},
+		{permissions: "r", want: unix.O_RDONLY},
+		{permissions: "w", want: unix.O_WRONLY},
+		{permissions: "m", want: unix.O_RDONLY},
+	} {
+		if got := openFlags(tc.permissions); got != tc.want {
+			t.Errorf("openFlags(%q) = %d, want %d", tc.permissions, got, tc.want)
+		}
+	}
+}
+
+func TestNodeChecker(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		hc     HealthCheck
+		path   string
+		reason string
+	}{
+		{name: "device node", hc: HealthCheckNode, path: "/dev/null"},
+		{name: "open device node", hc: HealthCheckOpen, path: "/dev/null"},
+		{name: "regular file", hc: HealthCheckNode, path: file, reason: healthcheck.ReasonNotDevice},
+		{name: "missing", hc: HealthCheckOpen, path: file + ".missing", reason: healthcheck.ReasonNotFound},
+		{name: "disabled", hc: HealthCheckNone, path: file + ".missing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := nodeChecker(tc.hc, "rw").Check(tc.path)
+			switch {
+			case tc.reason == "" && err != nil:
+				t.Errorf("Check(%q) = %v, want nil", tc.path, err)
+			case tc.reason != "" && healthcheck.Reason(err) != tc.reason:
+				t.Errorf("Check(%q) = %v, want reason %s", tc.path, err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	if _, err := os.Stat("/dev/null"); err != nil {
+		t.Skip("/dev/null is not available")
+	}
+
+	unhealthy := healthcheck.CheckerFunc(func(path string) error {
+		if path == "/dev/zero" {
+			return &healthcheck.Error{Reason: healthcheck.ReasonOpenFailed, Err: errors.New("test")}
+		}
+		return nil
+	})
+	group := Group{
+		Name:     "test",
+		PerMatch: true,
+		Paths: []Path{
+			{Path: "/dev/null", ContainerPath: "/dev/test", Permissions: "rw"},
+			{Path: "/dev/zero", Permissions: "r"},
+		},
+	}
+	s := New("example.com", group, cdi.Config{Mode: cdi.ModeDeviceSpec}, unhealthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	if got, want := s.Socket(), "unix:///var/lib/kubelet/device-plugins/generic-test.sock"; got != want {
+		t.Errorf("Socket() = %q, want %q", got, want)
+	}
+
+	health := map[string]string{}
+	for _, dev := range s.Advertised() {
+		health[dev.ID] = dev.Health
+	}
+	if health["null"] != v1beta1.Healthy || health["zero"] != v1beta1.Unhealthy || len(health) != 2 {
+		t.Fatalf("advertised devices = %v, want healthy null and unhealthy zero", health)
+	}
+
+	res, err := s.Allocate(t.Context(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"null"}}},
+	})
+	if err != nil {
+		t.Fatalf("Allocate() = %v", err)
+	}
+	specs := res.ContainerResponses[0].Devices
+	if len(specs) != 1 || specs[0].HostPath != "/dev/null" || specs[0].ContainerPath != "/dev/test" {
+		t.Errorf("Allocate() devices = %v, want /dev/null at /dev/test", specs)
+	}
+
+	// device nodes disappearing after the devices were published
+	s.mu.Lock()
+	delete(s.specs, "null")
+	s.mu.Unlock()
+	_, err = s.Allocate(t.Context(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"null"}}},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Allocate() = %v, want %v", err, codes.NotFound)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+)
+
+const (
+	// KVM_GET_API_VERSION, _IO(KVMIO, 0x00)
+	kvmGetAPIVersion = 0xAE00
+	// KVM_CHECK_EXTENSION, _IO(KVMIO, 0x03)
+	kvmCheckExtension = 0xAE03
+)
+
+// Capability is a KVM capability queried with KVM_CHECK_EXTENSION.
+type Capability struct {
+	// Name is used in metrics, logs and feature labels.
+	Name string
+	// ID is the KVM_CAP_* number of the capability.
+	ID uintptr
+	// Numeric is set if the result is a value, e.g. a limit, rather than a flag.
+	Numeric bool
+}
+
+// Capabilities are the capabilities probed on discovery.
+var Capabilities = []Capability{
+	{Name: "max_vcpus", ID: 66, Numeric: true}, // KVM_CAP_MAX_VCPUS
+	{Name: "nested_state", ID: 157},            // KVM_CAP_NESTED_STATE
+	{Name: "dirty_log_ring", ID: 192},          // KVM_CAP_DIRTY_LOG_RING
+	{Name: "guest_memfd", ID: 234},             // KVM_CAP_GUEST_MEMFD
+}
+
+// Device is the ioctl interface of an opened KVM device, so that probing does not
+// depend on /dev/kvm, e.g. in tests.
+type Device interface {
+	// APIVersion returns the result of KVM_GET_API_VERSION.
+	APIVersion() (int, error)
+	// CheckExtension returns the result of KVM_CHECK_EXTENSION, which is 0 if the
+	// capability is not supported.
+	CheckExtension(capability uintptr) (int, error)
+	Close() error
+}
+
+// OpenFunc opens the KVM device at path.
+type OpenFunc func(path string) (Device, error)
+
+var _ OpenFunc = Open
+
+// Open opens the KVM device at path using ioctls of the kernel.
+func Open(path string) (Device, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &device{fd: fd}, nil
+}
+
+type device struct {
+	fd int
+}
+
+func (d *device) APIVersion() (int, error) {
+	return unix.IoctlRetInt(d.fd, kvmGetAPIVersion)
+}
+
+func (d *device) CheckExtension(capability uintptr) (int, error) {
+	ret, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(d.fd), kvmCheckExtension, capability)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(ret), nil
+}
+
+func (d *device) Close() error {
+	return unix.Close(d.fd)
+}
+
+// APIVersionChecker returns a Checker verifying that the device opened with open
+// implements the stable KVM API.
+func APIVersionChecker(open OpenFunc) healthcheck.Checker {
+	return healthcheck.CheckerFunc(func(path string) error {
+		dev, err := open(path)
+		if err != nil {
+			return healthcheck.OpenError(err)
+		}
+		defer dev.Close() //nolint:errcheck // best effort call
+
+		version, err := dev.APIVersion()
+		if err != nil {
+			return &healthcheck.Error{
+				Reason: healthcheck.ReasonProbeFailed,
+				Err:    fmt.Errorf("KVM_GET_API_VERSION failed: %w", err),
+			}
+		}
+		if version != kvmAPIVersion {
+			return &healthcheck.Error{
+				Reason: healthcheck.ReasonUnsupportedAPI,
+				Err:    fmt.Errorf("unsupported KVM API version %d, expected %d", version, kvmAPIVersion),
+			}
+		}
+		return nil
+	})
+}
+
+// probeCapabilities queries all Capabilities of the device opened with open.
+func probeCapabilities(open OpenFunc, path string) (map[string]int, error) {
+	dev, err := open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer dev.Close() //nolint:errcheck // best effort call
+
+	caps := make(map[string]int, len(Capabilities))
+	for _, c := range Capabilities {
+		v, err := dev.CheckExtension(c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("KVM_CHECK_EXTENSION(%s) failed: %w", c.Name, err)
+		}
+		caps[c.Name] = v
+	}
+	return caps, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+)
+
+// fakeDevice answers the KVM ioctls with fixed values.
+type fakeDevice struct {
+	version    int
+	versionErr error
+	// caps maps KVM_CAP_* numbers to results, missing capabilities are unsupported
+	caps    map[uintptr]int
+	capsErr error
+
+	closed bool
+}
+
+func (d *fakeDevice) APIVersion() (int, error) {
+	return d.version, d.versionErr
+}
+
+func (d *fakeDevice) CheckExtension(capability uintptr) (int, error) {
+	return d.caps[capability], d.capsErr
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+// open returns an OpenFunc opening dev, or failing with err.
+func open(dev *fakeDevice, err error) OpenFunc {
+	return func(string) (Device, error) {
+		if err != nil {
+			return nil, err
+		}
+		return dev, nil
+	}
+}
+
+func TestAPIVersionChecker(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		dev     *fakeDevice
+		openErr error
+		reason  string
+	}{
+		{
+			name: "supported",
+			dev:  &fakeDevice{version: kvmAPIVersion},
+		},
+		{
+			name:   "unsupported",
+			dev:    &fakeDevice{version: kvmAPIVersion - 1},
+			reason: healthcheck.ReasonUnsupportedAPI,
+		},
+		{
+			name:   "ioctl failure",
+			dev:    &fakeDevice{versionErr: unix.ENOTTY},
+			reason: healthcheck.ReasonProbeFailed,
+		},
+		{
+			name:    "missing",
+			openErr: fs.ErrNotExist,
+			reason:  healthcheck.ReasonNotFound,
+		},
+		{
+			name:    "permission denied",
+			openErr: unix.EACCES,
+			reason:  healthcheck.ReasonPermission,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := APIVersionChecker(open(tc.dev, tc.openErr)).Check(kvmPath)
+			switch {
+			case tc.reason == "" && err != nil:
+				t.Errorf("Check() = %v, want nil", err)
+			case tc.reason != "" && (err == nil || healthcheck.Reason(err) != tc.reason):
+				t.Errorf("Check() = %v, want reason %q", err, tc.reason)
+			}
+			if tc.dev != nil && !tc.dev.closed {
+				t.Error("device was not closed")
+			}
+		})
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	t.Parallel()
+
+	t.Run("supported", func(t *testing.T) {
+		t.Parallel()
+
+		dev := &fakeDevice{caps: map[uintptr]int{66: 1024, 157: 1}}
+		caps, err := probeCapabilities(open(dev, nil), kvmPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]int{"max_vcpus": 1024, "nested_state": 1, "dirty_log_ring": 0, "guest_memfd": 0}
+		if !maps.Equal(caps, want) {
+			t.Errorf("probeCapabilities() = %v, want %v", caps, want)
+		}
+		if !dev.closed {
+			t.Error("device was not closed")
+		}
+	})
+
+	t.Run("ioctl failure", func(t *testing.T) {
+		t.Parallel()
+
+		dev := &fakeDevice{capsErr: unix.EINVAL}
+		if _, err := probeCapabilities(open(dev, nil), kvmPath); !errors.Is(err, unix.EINVAL) {
+			t.Errorf("probeCapabilities() = %v, want %v", err, unix.EINVAL)
+		}
+		if !dev.closed {
+			t.Error("device was not closed")
+		}
+	})
+
+	t.Run("open failure", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := probeCapabilities(open(nil, fs.ErrPermission), kvmPath); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("probeCapabilities() = %v, want %v", err, fs.ErrPermission)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		t.Parallel()
+
+		// failed probes are logged and leave the capabilities empty
+		s := &Server{log: slog.New(slog.DiscardHandler), open: open(nil, fs.ErrNotExist)}
+		if caps := s.probeCapabilities(); len(caps) != 0 {
+			t.Errorf("probeCapabilities() = %v, want empty", caps)
+		}
+
+		s.open = open(&fakeDevice{caps: map[uintptr]int{234: 1}}, nil)
+		if caps := s.probeCapabilities(); caps["guest_memfd"] != 1 {
+			t.Errorf("probeCapabilities() = %v, want guest_memfd", caps)
+		}
+	})
+}
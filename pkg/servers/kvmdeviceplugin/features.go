@@ -0,0 +1,143 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"bufio"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+)
+
+const (
+	sysModulePath = "/sys/module"
+	cpuinfoPath   = "/proc/cpuinfo"
+)
+
+// vendors maps KVM vendor modules to their vendor and the CPU flag of hardware virtualization.
+var vendors = []struct {
+	name   string
+	module string
+	flag   string
+}{
+	{name: "intel", module: "kvm_intel", flag: "vmx"},
+	{name: "amd", module: "kvm_amd", flag: "svm"},
+}
+
+var _ features.Source = (*Server)(nil)
+
+// Features reports the KVM vendor, whether nested virtualization is enabled and the
+// capabilities of KVM found by the last discovery. No features are reported without
+// /dev/kvm.
+func (s *Server) Features() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.labels()
+}
+
+// labels returns the features, the caller must hold s.mu.
+func (s *Server) labels() map[string]string {
+	// features are published by the kvm resource only
+	if _, err := os.Stat(kvmPath); err != nil || s.nested {
+		return map[string]string{}
+	}
+
+	labels := map[string]string{}
+	for _, c := range Capabilities {
+		v, ok := s.caps[c.Name]
+		if !ok {
+			continue
+		}
+
+		name := "kvm." + strings.ReplaceAll(c.Name, "_", "-")
+		if c.Numeric {
+			labels[name] = strconv.Itoa(v)
+		} else {
+			labels[name] = features.Bool(v > 0)
+		}
+	}
+
+	maps.Copy(labels, vendorLabels(sysModulePath, cpuinfoPath))
+	return labels
+}
+
+// vendorLabels probes the KVM vendor and whether nested virtualization is enabled. The
+// vendor is taken from the vendor module loaded in moduleDir, or from the CPU flags in
+// cpuinfo if no module is loaded.
+func vendorLabels(moduleDir, cpuinfo string) map[string]string {
+	labels := map[string]string{}
+	for _, v := range vendors {
+		if _, err := os.Stat(filepath.Join(moduleDir, v.module)); err != nil {
+			continue
+		}
+
+		labels["kvm.vendor"] = v.name
+		if nested, ok := moduleParameter(moduleDir, v.module, "nested"); ok {
+			labels["kvm.nested"] = features.Bool(nested == "Y" || nested == "1")
+		}
+		return labels
+	}
+
+	flags := cpuFlags(cpuinfo)
+	for _, v := range vendors {
+		if slices.Contains(flags, v.flag) {
+			labels["kvm.vendor"] = v.name
+			break
+		}
+	}
+	return labels
+}
+
+// nestedEnabled reports whether nested virtualization is enabled in the loaded KVM
+// vendor module.
+func nestedEnabled(moduleDir string) bool {
+	for _, v := range vendors {
+		if nested, ok := moduleParameter(moduleDir, v.module, "nested"); ok {
+			return nested == "Y" || nested == "1"
+		}
+	}
+	return false
+}
+
+func moduleParameter(moduleDir, module, param string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(moduleDir, module, "parameters", param))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// cpuFlags returns the flags of the first CPU in cpuinfo, e.g. /proc/cpuinfo.
+func cpuFlags(cpuinfo string) []string {
+	f, err := os.Open(cpuinfo)
+	if err != nil {
+		return nil
+	}
+	defer f.Close() //nolint:errcheck // best effort call
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == "flags" {
+			return strings.Fields(value)
+		}
+	}
+	return nil
+}
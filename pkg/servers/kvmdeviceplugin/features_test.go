@@ -0,0 +1,122 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSys creates a fake /sys/module tree with the given module parameters, and a fake
+// /proc/cpuinfo with the given flags.
+func fakeSys(t *testing.T, params map[string]string, flags string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	moduleDir := filepath.Join(dir, "module")
+	for param, value := range params {
+		path := filepath.Join(moduleDir, param)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if value == "" {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cpuinfo := filepath.Join(dir, "cpuinfo")
+	data := "processor\t: 0\nvendor_id\t: test\nflags\t\t: " + flags + "\n\nprocessor\t: 1\nflags\t\t: other\n"
+	if err := os.WriteFile(cpuinfo, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return moduleDir, cpuinfo
+}
+
+func TestVendorLabels(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		params map[string]string
+		flags  string
+		want   map[string]string
+		nested bool
+	}{
+		{
+			name:   "intel nested",
+			params: map[string]string{"kvm_intel/parameters/nested": "Y"},
+			flags:  "fpu vmx sse",
+			want:   map[string]string{"kvm.vendor": "intel", "kvm.nested": "true"},
+			nested: true,
+		},
+		{
+			name:   "amd nested",
+			params: map[string]string{"kvm_amd/parameters/nested": "1"},
+			flags:  "fpu svm sse",
+			want:   map[string]string{"kvm.vendor": "amd", "kvm.nested": "true"},
+			nested: true,
+		},
+		{
+			name:   "intel not nested",
+			params: map[string]string{"kvm_intel/parameters/nested": "N"},
+			flags:  "fpu vmx sse",
+			want:   map[string]string{"kvm.vendor": "intel", "kvm.nested": "false"},
+		},
+		{
+			name:   "module without parameter",
+			params: map[string]string{"kvm_amd/parameters/other": ""},
+			want:   map[string]string{"kvm.vendor": "amd"},
+		},
+		{
+			name:  "cpu flags",
+			flags: "fpu svm sse",
+			want:  map[string]string{"kvm.vendor": "amd"},
+		},
+		{
+			name:  "unknown",
+			flags: "fpu sse",
+			want:  map[string]string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			moduleDir, cpuinfo := fakeSys(t, tc.params, tc.flags)
+			if got := vendorLabels(moduleDir, cpuinfo); !maps.Equal(got, tc.want) {
+				t.Errorf("vendorLabels() = %v, want %v", got, tc.want)
+			}
+			if got := nestedEnabled(moduleDir); got != tc.nested {
+				t.Errorf("nestedEnabled() = %v, want %v", got, tc.nested)
+			}
+		})
+	}
+}
+
+func TestCPUFlags(t *testing.T) {
+	t.Parallel()
+
+	_, cpuinfo := fakeSys(t, nil, "fpu vmx")
+	if got := cpuFlags(cpuinfo); len(got) != 2 || got[0] != "fpu" || got[1] != "vmx" {
+		t.Errorf("cpuFlags() = %v, want the flags of the first CPU", got)
+	}
+	if got := cpuFlags(filepath.Join(t.TempDir(), "missing")); got != nil {
+		t.Errorf("cpuFlags() = %v for a missing file, want nil", got)
+	}
+}
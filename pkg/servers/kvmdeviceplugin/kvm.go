@@ -16,124 +16,438 @@ package kvmdeviceplugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/podresources"
 
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
 const (
-	kvmPath = "/dev/kvm"
-	kvmName = "kvm"
-	rwPerm  = "rw"
+	kvmPath       = "/dev/kvm"
+	kvmName       = "kvm"
+	kvmNestedName = "kvm-nested"
+	rwPerm        = "rw"
+
+	kvmMajor      = 10
+	kvmMinor      = 232
+	kvmAPIVersion = 12
+)
+
+// DefaultHealthChecker verifies that /dev/kvm is the KVM misc device, that it can be
+// opened and that it implements the stable KVM API.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.CharDevice(kvmMajor, kvmMinor),
+	APIVersionChecker(Open),
 )
 
 type Server struct {
+	plugin.Base
+
 	log       *slog.Logger
 	namespace string
-	update    chan struct{}
+	name      string
+	nested    bool
 	devices   uint
-	devs      []*v1beta1.Device
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+	open      OpenFunc
+	slots     *slots
+	features  *features.File
+
+	mu    sync.RWMutex
+	state state
+	caps  map[string]int
+}
+
+// state is the state of /dev/kvm found by the last discovery.
+type state struct {
+	present bool
+	health  string
+	// nested is set if nested virtualization is enabled
+	nested bool
 }
 
-var _ v1beta1.DevicePluginServer = (*Server)(nil)
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+	_ podresources.Subscriber    = (*Server)(nil)
+)
 
-func New(namespace string, devices uint, log *slog.Logger) *Server {
+// New creates the KVM device plugin server. If checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	devices uint,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
 	if log == nil {
 		log = slog.New(slog.DiscardHandler)
 	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
 	s := &Server{
 		log:       log,
 		namespace: namespace,
+		name:      kvmName,
 		devices:   devices,
-		update:    make(chan struct{}),
-		devs:      []*v1beta1.Device{},
+		cdi:       cdiConfig,
+		checker:   checker,
+		open:      Open,
+		slots:     newSlots(),
+		caps:      map[string]int{},
 	}
-	s.discover()
+	s.init()
 	return s
 }
 
-func (s *Server) discover() {
-	if _, err := os.Stat(kvmPath); err == nil {
-		s.log.Debug("Discovered KVM device")
+// Nested creates the server of the kvm-nested resource. It advertises /dev/kvm only
+// while nested virtualization is enabled, and shares the budget of devices with s: a
+// device allocated from one resource is no longer advertised by the other. Allocations
+// are tracked with the kubelet PodResources API, so both servers must be subscribed to
+// a podresources.Reconciler.
+func (s *Server) Nested() *Server {
+	n := &Server{
+		log:       s.log,
+		namespace: s.namespace,
+		name:      kvmNestedName,
+		nested:    true,
+		devices:   s.devices,
+		cdi:       s.cdi,
+		checker:   s.checker,
+		open:      s.open,
+		slots:     s.slots,
+		caps:      map[string]int{},
+	}
+	n.init()
+	return n
+}
+
+func (s *Server) init() {
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	s.slots.subscribe(s.refresh)
+
+	if err := s.Discover(); err != nil {
+		s.log.Error("Initial discovery failed", "resource", s.Name(), "error", err)
+	}
+	s.Update()
+	if !s.state.present {
+		s.log.Warn("No KVM device found", "resource", s.Name())
+	}
+}
+
+// Discover checks whether the KVM device is present and healthy, probes its capabilities
+// and rebuilds the list of advertised devices. Changes are published to kubelet on the
+// next call to Update.
+func (s *Server) Discover() error {
+	var herr error
+	st := state{health: v1beta1.Healthy}
+	caps := map[string]int{}
+
+	_, err := os.Stat(kvmPath)
+	switch {
+	case err == nil:
+		st.present = true
+		if herr = s.checker.Check(kvmPath); herr != nil {
+			st.health = v1beta1.Unhealthy
+		} else if !s.nested {
+			caps = s.probeCapabilities()
+		}
+		st.nested = nestedEnabled(sysModulePath)
+	case errors.Is(err, fs.ErrNotExist):
+		// device is not present (yet), advertise no devices
+	default:
+		return fmt.Errorf("failed to stat %s: %w", kvmPath, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	if !s.nested {
+		s.updateCapabilities(caps)
+	}
+
+	if st != s.state {
+		s.logTransition(s.state, st, herr)
+		s.state = st
+	}
+
+	s.rebuild()
+	return nil
+}
+
+func (s *Server) logTransition(prev, st state, herr error) {
+	log := s.log.With("resource", s.Name())
+
+	switch {
+	case !st.present:
+		if prev.present {
+			log.Warn("KVM device disappeared")
+		}
+	case st.health != v1beta1.Healthy:
+		if prev.health != st.health || !prev.present {
+			reason := healthcheck.Reason(herr)
+			log.Warn("KVM device is unhealthy", "reason", reason, "error", herr)
+			metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+		}
+	case !prev.present:
+		log.Info("Discovered KVM device", "devices", s.devices, "nested", st.nested)
+	case prev.health != st.health:
+		log.Info("KVM device is healthy")
+	}
+
+	if s.nested && st.present && prev.nested != st.nested {
+		log.Info("Nested virtualization changed", "enabled", st.nested)
+	}
+}
+
+// rebuild rebuilds the list of advertised devices from the last discovered state,
+// leaving out slots held by the other resource. The caller must hold s.mu.
+func (s *Server) rebuild() {
+	devs := []*v1beta1.Device{}
+	if s.advertised() {
 		for i := uint(0); i < s.devices; i++ {
-			s.devs = append(s.devs, &v1beta1.Device{
-				ID:     fmt.Sprintf("%s%d", kvmName, i),
-				Health: v1beta1.Healthy,
+			if !s.slots.available(i, s.Name()) {
+				continue
+			}
+			devs = append(devs, &v1beta1.Device{
+				ID:     s.deviceID(i),
+				Health: s.state.health,
 			})
 		}
-	} else {
-		s.log.Error("No KVM device found")
 	}
+
+	if plugin.EqualDevices(devs, s.Devices()) {
+		return
+	}
+
+	s.SetDevices(devs)
 }
 
-func (s *Server) Name() string {
-	return path.Join(s.namespace, kvmName)
+// advertised reports whether the devices are advertised at all. The caller must hold s.mu.
+func (s *Server) advertised() bool {
+	return s.state.present && (!s.nested || s.state.nested)
 }
 
-func (s *Server) Socket() string {
-	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, kvmName+".sock"))
+// refresh rebuilds and publishes the device list after holders of slots changed.
+func (s *Server) refresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rebuild()
+	s.Sync(s.cdiSpec)
 }
 
-func (s *Server) GetDevicePluginOptions(
-	ctx context.Context,
-	_ *v1beta1.Empty,
-) (*v1beta1.DevicePluginOptions, error) {
-	return &v1beta1.DevicePluginOptions{
-		PreStartRequired:                false,
-		GetPreferredAllocationAvailable: false,
-	}, nil
+// probeCapabilities returns the capabilities of the KVM device. Capabilities are only
+// informational, so failures do not affect the health of the device.
+func (s *Server) probeCapabilities() map[string]int {
+	caps, err := probeCapabilities(s.open, kvmPath)
+	if err != nil {
+		s.log.Warn("Failed to probe KVM capabilities", "error", err)
+		return map[string]int{}
+	}
+	return caps
 }
 
-func (s *Server) ListAndWatch(
-	_ *v1beta1.Empty,
-	lws v1beta1.DevicePlugin_ListAndWatchServer,
-) error {
-	if err := lws.Send(&v1beta1.ListAndWatchResponse{Devices: s.devs}); err != nil {
-		s.log.Error("Failed to send ListAndWatch response", "error", err)
+// updateCapabilities records changed capabilities in logs and metrics.
+func (s *Server) updateCapabilities(caps map[string]int) {
+	if maps.Equal(caps, s.caps) {
+		return
 	}
 
-	for range s.update {
-		if err := lws.Send(&v1beta1.ListAndWatchResponse{Devices: s.devs}); err != nil {
-			s.log.Error("Failed to send ListAndWatch response", "error", err)
+	metrics.KVMCapabilities.Reset()
+	if len(caps) > 0 {
+		attrs := make([]any, 0, 2*len(caps))
+		for _, c := range Capabilities {
+			attrs = append(attrs, c.Name, caps[c.Name])
+			metrics.KVMCapabilities.WithLabelValues(c.Name).Set(float64(caps[c.Name]))
 		}
+		s.log.Info("KVM capabilities", attrs...)
 	}
 
-	panic("unexpected error")
+	s.caps = caps
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.updateFeatures()
+	s.Sync(s.cdiSpec)
+}
+
+// cdiSpec returns the CDI spec of all slots, not only of the advertised ones, so that
+// the spec does not change whenever a slot is taken by the other resource.
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	if !s.advertised() {
+		return spec
+	}
+
+	for i := uint(0); i < s.devices; i++ {
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name: s.deviceID(i),
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        kvmPath,
+						HostPath:    kvmPath,
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+func (s *Server) deviceID(slot uint) string {
+	return fmt.Sprintf("%s%d", s.name, slot)
+}
+
+// slot returns the slot of a device ID of this resource.
+func (s *Server) slot(id string) (uint, error) {
+	n, ok := strings.CutPrefix(id, s.name)
+	if !ok {
+		return 0, fmt.Errorf("invalid device ID %q", id)
+	}
+	slot, err := strconv.ParseUint(n, 10, 0)
+	if err != nil || uint(slot) >= s.devices {
+		return 0, fmt.Errorf("invalid device ID %q", id)
+	}
+	return uint(slot), nil
+}
+
+// UpdateAllocations updates the slots held by the kvm and kvm-nested resources.
+func (s *Server) UpdateAllocations(allocations podresources.Allocations) {
+	held := map[uint]string{}
+	for _, name := range []string{kvmName, kvmNestedName} {
+		resource := path.Join(s.namespace, name)
+		for id := range allocations[resource] {
+			n, ok := strings.CutPrefix(id, name)
+			if !ok {
+				continue
+			}
+			if slot, err := strconv.ParseUint(n, 10, 0); err == nil {
+				held[uint(slot)] = resource
+			}
+		}
+	}
+	s.slots.update(held)
+}
+
+// SetFeatureFile enables writing device features to the feature file, which is
+// refreshed on every update.
+func (s *Server) SetFeatureFile(f *features.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.features = f
+	s.updateFeatures()
+}
+
+func (s *Server) updateFeatures() {
+	if s.features == nil {
+		return
+	}
+	if err := s.features.Write(s.labels()); err != nil {
+		s.log.Error("Failed to update feature file", "path", s.features.Path(), "error", err)
+	}
+}
+
+// WatchPaths returns the device node paths watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	return []string{kvmPath}
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, s.name)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, s.name+".sock"))
 }
 
 func (s *Server) Allocate(
 	ctx context.Context,
 	req *v1beta1.AllocateRequest,
 ) (*v1beta1.AllocateResponse, error) {
-	s.update <- struct{}{}
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
 
-	devices := []*v1beta1.DeviceSpec{
-		{
-			ContainerPath: kvmPath,
-			HostPath:      kvmPath,
-			Permissions:   rwPerm,
-		},
+	// slots of all containers are claimed at once, so that a failing container request
+	// does not leave slots of the previous ones claimed
+	var ids []string
+	for _, creq := range req.ContainerRequests {
+		ids = append(ids, creq.DevicesIDs...)
+	}
+	if err := s.claim(ids); err != nil {
+		s.log.Error("Rejecting allocation", "devices", ids, "error", err)
+		return nil, err
 	}
 
-	return &v1beta1.AllocateResponse{
-		ContainerResponses: []*v1beta1.ContainerAllocateResponse{{Devices: devices}},
-	}, nil
-}
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
 
-func (s *Server) GetPreferredAllocation(
-	ctx context.Context,
-	req *v1beta1.PreferredAllocationRequest,
-) (*v1beta1.PreferredAllocationResponse, error) {
-	return &v1beta1.PreferredAllocationResponse{}, nil
+	for _, creq := range req.ContainerRequests {
+		cres := &v1beta1.ContainerAllocateResponse{}
+		if s.cdi.Mode.DeviceSpec() {
+			cres.Devices = []*v1beta1.DeviceSpec{
+				{
+					ContainerPath: kvmPath,
+					HostPath:      kvmPath,
+					Permissions:   rwPerm,
+				},
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
 }
 
-func (s *Server) PreStartContainer(
-	ctx context.Context,
-	req *v1beta1.PreStartContainerRequest,
-) (*v1beta1.PreStartContainerResponse, error) {
-	return &v1beta1.PreStartContainerResponse{}, nil
+// claim marks the slots of the devices as held by this resource, so that the other
+// resource stops advertising them.
+func (s *Server) claim(ids []string) error {
+	slots := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		slot, err := s.slot(id)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		slots = append(slots, slot)
+	}
+
+	if err := s.slots.claim(s.Name(), slots); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
 }
@@ -0,0 +1,132 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// newTestServer returns a server with a present and healthy KVM device, without running
+// discovery on the host.
+func newTestServer(t *testing.T, name string, slots *slots) *Server {
+	t.Helper()
+
+	s := &Server{
+		log:       slog.New(slog.DiscardHandler),
+		namespace: "devices.anza-labs.dev",
+		name:      name,
+		nested:    name == kvmNestedName,
+		devices:   4,
+		cdi:       cdi.Config{Mode: cdi.ModeDeviceSpec},
+		checker:   DefaultHealthChecker,
+		open:      open(&fakeDevice{version: kvmAPIVersion}, nil),
+		slots:     slots,
+		caps:      map[string]int{},
+		state:     state{present: true, health: v1beta1.Healthy, nested: true},
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+	s.rebuild()
+	s.Sync(s.cdiSpec)
+	return s
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	slots := newSlots()
+	kvm := newTestServer(t, kvmName, slots)
+	nested := newTestServer(t, kvmNestedName, slots)
+
+	res, err := kvm.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"kvm0"}},
+			{DevicesIDs: []string{"kvm1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.ContainerResponses) != 2 || len(res.ContainerResponses[0].Devices) != 1 {
+		t.Fatalf("Allocate() = %v, want a device for each container", res)
+	}
+	for _, slot := range []uint{0, 1} {
+		if slots.available(slot, nested.Name()) {
+			t.Errorf("slot %d is available to %s after allocation", slot, nested.Name())
+		}
+	}
+}
+
+func TestAllocateReleasesOnFailure(t *testing.T) {
+	t.Parallel()
+
+	slots := newSlots()
+	kvm := newTestServer(t, kvmName, slots)
+	nested := newTestServer(t, kvmNestedName, slots)
+
+	// slot 1 is taken by the other resource before kvm advertises the change
+	if err := slots.claim(nested.Name(), []uint{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := kvm.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"kvm0"}},
+			{DevicesIDs: []string{"kvm2"}},
+			{DevicesIDs: []string{"kvm1"}},
+		},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Allocate() = %v, want %v", err, codes.FailedPrecondition)
+	}
+
+	// slots of the containers before the failing one must not stay claimed
+	for _, slot := range []uint{0, 2} {
+		if !slots.available(slot, nested.Name()) {
+			t.Errorf("slot %d is still claimed after a failed allocation", slot)
+		}
+	}
+}
+
+func TestAllocateInvalidID(t *testing.T) {
+	t.Parallel()
+
+	kvm := newTestServer(t, kvmName, newSlots())
+	kvm.SetDevices(append(kvm.Devices(), &v1beta1.Device{ID: "kvm9", Health: v1beta1.Healthy}))
+	kvm.Sync(kvm.cdiSpec)
+
+	_, err := kvm.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"kvm0"}},
+			{DevicesIDs: []string{"kvm9"}},
+		},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Allocate() = %v, want %v", err, codes.InvalidArgument)
+	}
+	if !kvm.slots.available(0, "devices.anza-labs.dev/"+kvmNestedName) {
+		t.Error("slot 0 is claimed after a failed allocation")
+	}
+}
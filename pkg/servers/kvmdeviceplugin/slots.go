@@ -0,0 +1,136 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvmdeviceplugin
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// claimTTL is how long slots claimed by Allocate are considered in use, until the
+// allocation shows up in the kubelet PodResources API.
+const claimTTL = 2 * time.Minute
+
+// slots is the budget of /dev/kvm devices shared by the kvm and kvm-nested resources.
+// A slot held by one resource is not advertised by the other. Slots are held either
+// according to the PodResources API, or since they were claimed by Allocate.
+type slots struct {
+	mu        sync.Mutex
+	held      map[uint]string
+	claims    map[uint]claim
+	listeners []func()
+}
+
+type claim struct {
+	resource string
+	expires  time.Time
+}
+
+func newSlots() *slots {
+	return &slots{
+		held:   map[uint]string{},
+		claims: map[uint]claim{},
+	}
+}
+
+// subscribe registers fn to be called whenever the holders of slots change.
+func (p *slots) subscribe(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.listeners = append(p.listeners, fn)
+}
+
+// available reports whether the slot can be advertised by the resource.
+func (p *slots) available(slot uint, resource string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	holder, ok := p.holder(slot, time.Now())
+	return !ok || holder == resource
+}
+
+// claim marks the slots as held by the resource, unless any of them is held by another
+// resource.
+func (p *slots) claim(resource string, ids []uint) error {
+	p.mu.Lock()
+
+	now := time.Now()
+	for _, slot := range ids {
+		if holder, ok := p.holder(slot, now); ok && holder != resource {
+			p.mu.Unlock()
+			return fmt.Errorf("slot %d is held by %s", slot, holder)
+		}
+	}
+
+	changed := false
+	for _, slot := range ids {
+		if _, ok := p.held[slot]; ok {
+			continue
+		}
+		if _, ok := p.claims[slot]; !ok {
+			changed = true
+		}
+		p.claims[slot] = claim{resource: resource, expires: now.Add(claimTTL)}
+	}
+
+	p.mu.Unlock()
+	if changed {
+		p.notify()
+	}
+	return nil
+}
+
+// update replaces the slots held according to the PodResources API. Claims are dropped
+// once their slot is reported, or when they expire.
+func (p *slots) update(held map[uint]string) {
+	p.mu.Lock()
+
+	now := time.Now()
+	changed := !maps.Equal(p.held, held)
+	for slot, c := range p.claims {
+		if _, ok := held[slot]; ok || now.After(c.expires) {
+			delete(p.claims, slot)
+			changed = true
+		}
+	}
+	p.held = held
+
+	p.mu.Unlock()
+	if changed {
+		p.notify()
+	}
+}
+
+func (p *slots) holder(slot uint, now time.Time) (string, bool) {
+	if resource, ok := p.held[slot]; ok {
+		return resource, true
+	}
+	if c, ok := p.claims[slot]; ok && now.Before(c.expires) {
+		return c.resource, true
+	}
+	return "", false
+}
+
+func (p *slots) notify() {
+	p.mu.Lock()
+	listeners := p.listeners
+	p.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
+}
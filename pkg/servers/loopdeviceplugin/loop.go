@@ -0,0 +1,374 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loopdeviceplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	loopPrefix      = "/dev/loop"
+	loopPattern     = loopPrefix + "[0-9]*"
+	loopControlPath = "/dev/loop-control"
+	sysBlockPath    = "/sys/block"
+	loopName        = "loop"
+	rwPerm          = "rw"
+
+	loopMajor = 7
+)
+
+// DefaultHealthChecker verifies that the device node is a loop block device and that it
+// can be opened.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.BlockDevice(loopMajor),
+	healthcheck.Open(unix.O_RDWR),
+)
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	namespace string
+	pool      uint
+	control   bool
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+	sysBlock  string
+
+	mu sync.RWMutex
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the loop device plugin server, advertising every /dev/loopN node as a
+// separate device. Missing loop devices below the pool size are created through
+// /dev/loop-control, which is also passed to containers if control is set. If checker
+// is nil, DefaultHealthChecker is used. Loop devices bound to a backing file are
+// advertised as unhealthy, regardless of the checker.
+func New(
+	namespace string,
+	pool uint,
+	control bool,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	s := &Server{
+		log:       log,
+		namespace: namespace,
+		pool:      pool,
+		control:   control,
+		cdi:       cdiConfig,
+		checker:   checker,
+		sysBlock:  sysBlockPath,
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		log.Warn("No loop device found")
+	}
+	return s
+}
+
+// Discover creates missing loop devices of the pool, then lists loop device nodes and
+// rebuilds the list of advertised devices. Changes are published to kubelet on the next
+// call to Update.
+func (s *Server) Discover() error {
+	if err := s.createPool(); err != nil {
+		// devices that already exist are advertised nonetheless
+		s.log.Error("Failed to create loop devices", "error", err)
+	}
+
+	indexes, err := list()
+	if err != nil {
+		return err
+	}
+
+	devs := make([]*v1beta1.Device, 0, len(indexes))
+	unhealthy := 0
+	for _, n := range indexes {
+		health := v1beta1.Healthy
+		herr := s.checker.Check(devicePath(n))
+		if herr == nil {
+			herr = s.checkUnbound(n)
+		}
+		if herr != nil {
+			health = v1beta1.Unhealthy
+			unhealthy++
+			if !s.wasUnhealthy(deviceID(n)) {
+				reason := healthcheck.Reason(herr)
+				s.log.Warn("Loop device is unhealthy", "device", deviceID(n), "reason", reason, "error", herr)
+				metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+			}
+		}
+		devs = append(devs, &v1beta1.Device{
+			ID:     deviceID(n),
+			Health: health,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plugin.EqualDevices(devs, s.Devices()) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("Loop devices disappeared")
+	case len(devs) != len(s.Devices()):
+		s.log.Info("Discovered loop devices", "devices", len(devs), "unhealthy", unhealthy)
+	}
+
+	s.SetDevices(devs)
+	return nil
+}
+
+// wasUnhealthy reports whether the device was advertised as unhealthy before.
+func (s *Server) wasUnhealthy(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devs := s.Devices()
+	i := slices.IndexFunc(devs, func(dev *v1beta1.Device) bool { return dev.ID == id })
+	return i >= 0 && devs[i].Health == v1beta1.Unhealthy
+}
+
+// checkUnbound returns an error if the loop device is bound to a backing file, e.g. by
+// the host or by a container it was allocated to, so that it is not allocated again
+// until it is detached.
+func (s *Server) checkUnbound(n uint) error {
+	data, err := os.ReadFile(filepath.Join(s.sysBlock, deviceID(n), "loop", "backing_file"))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return &healthcheck.Error{
+			Reason: healthcheck.ReasonProbeFailed,
+			Err:    fmt.Errorf("failed to read backing file: %w", err),
+		}
+	}
+
+	return &healthcheck.Error{
+		Reason: healthcheck.ReasonInUse,
+		Err:    fmt.Errorf("bound to %s", strings.TrimSpace(string(data))),
+	}
+}
+
+// createPool adds the loop devices missing below the pool size through loop-control.
+func (s *Server) createPool() error {
+	if s.pool == 0 {
+		return nil
+	}
+
+	fd := -1
+	defer func() {
+		if fd >= 0 {
+			unix.Close(fd) //nolint:errcheck // best effort call
+		}
+	}()
+
+	for n := uint(0); n < s.pool; n++ {
+		if err := unix.Access(devicePath(n), unix.F_OK); err == nil {
+			continue
+		}
+
+		if fd < 0 {
+			var err error
+			fd, err = unix.Open(loopControlPath, unix.O_RDWR|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", loopControlPath, err)
+			}
+		}
+
+		if err := unix.IoctlSetInt(fd, unix.LOOP_CTL_ADD, int(n)); err != nil && !errors.Is(err, unix.EEXIST) {
+			return fmt.Errorf("failed to create %s: %w", devicePath(n), err)
+		}
+		s.log.Info("Created loop device", "device", deviceID(n))
+	}
+
+	return nil
+}
+
+// list returns the sorted indexes of all loop device nodes.
+func list() ([]uint, error) {
+	matches, err := filepath.Glob(loopPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loop devices: %w", err)
+	}
+
+	indexes := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		n, err := strconv.ParseUint(strings.TrimPrefix(m, loopPrefix), 10, 0)
+		if err != nil {
+			continue // e.g. partitions like /dev/loop0p1
+		}
+		indexes = append(indexes, uint(n))
+	}
+	slices.Sort(indexes)
+
+	return indexes, nil
+}
+
+func devicePath(n uint) string {
+	return loopPrefix + strconv.FormatUint(uint64(n), 10)
+}
+
+func deviceID(n uint) string {
+	return loopName + strconv.FormatUint(uint64(n), 10)
+}
+
+// hostPath returns the device node of the device ID.
+func hostPath(id string) (string, error) {
+	n, ok := strings.CutPrefix(id, loopName)
+	if !ok {
+		return "", fmt.Errorf("invalid device ID %q", id)
+	}
+	if _, err := strconv.ParseUint(n, 10, 0); err != nil {
+		return "", fmt.Errorf("invalid device ID %q", id)
+	}
+	return loopPrefix + n, nil
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		p, err := hostPath(dev.ID)
+		if err != nil {
+			continue
+		}
+		nodes := []*cdi.DeviceNode{
+			{
+				Path:        p,
+				HostPath:    p,
+				Permissions: rwPerm,
+			},
+		}
+		if s.control {
+			nodes = append(nodes, &cdi.DeviceNode{
+				Path:        loopControlPath,
+				HostPath:    loopControlPath,
+				Permissions: rwPerm,
+			})
+		}
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name:           dev.ID,
+			ContainerEdits: cdi.ContainerEdits{DeviceNodes: nodes},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the device node patterns watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	return []string{loopPattern}
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, loopName)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, loopName+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		cres := &v1beta1.ContainerAllocateResponse{}
+		if s.cdi.Mode.DeviceSpec() {
+			for _, id := range creq.DevicesIDs {
+				p, err := hostPath(id)
+				if err != nil {
+					return nil, status.Error(codes.InvalidArgument, err.Error())
+				}
+				cres.Devices = append(cres.Devices, &v1beta1.DeviceSpec{
+					ContainerPath: p,
+					HostPath:      p,
+					Permissions:   rwPerm,
+				})
+			}
+			if s.control {
+				cres.Devices = append(cres.Devices, &v1beta1.DeviceSpec{
+					ContainerPath: loopControlPath,
+					HostPath:      loopControlPath,
+					Permissions:   rwPerm,
+				})
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loopdeviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+)
+
+func TestCheckUnbound(t *testing.T) {
+	t.Parallel()
+
+	// fake /sys/block, loop0 is unbound and loop1 is bound to an image
+	sysBlock := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sysBlock, "loop0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(sysBlock, "loop1", "loop"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	backingFile := filepath.Join(sysBlock, "loop1", "loop", "backing_file")
+	if err := os.WriteFile(backingFile, []byte("/var/lib/images/disk.img\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{sysBlock: sysBlock}
+
+	if err := s.checkUnbound(0); err != nil {
+		t.Errorf("checkUnbound(0) = %v, want nil", err)
+	}
+	// missing devices are left to the health checker
+	if err := s.checkUnbound(2); err != nil {
+		t.Errorf("checkUnbound(2) = %v, want nil", err)
+	}
+
+	err := s.checkUnbound(1)
+	if reason := healthcheck.Reason(err); err == nil || reason != healthcheck.ReasonInUse {
+		t.Fatalf("checkUnbound(1) = %v, want reason %q", err, healthcheck.ReasonInUse)
+	}
+	if !strings.Contains(err.Error(), "/var/lib/images/disk.img") {
+		t.Errorf("checkUnbound(1) = %v, want the backing file", err)
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	t.Parallel()
+
+	for id, want := range map[string]string{
+		"loop0":  "/dev/loop0",
+		"loop12": "/dev/loop12",
+		"loop":   "",
+		"loopx":  "",
+		"kvm0":   "",
+	} {
+		got, err := hostPath(id)
+		switch {
+		case want == "" && err == nil:
+			t.Errorf("hostPath(%q) = %q, want error", id, got)
+		case want != "" && (err != nil || got != want):
+			t.Errorf("hostPath(%q) = %q, %v, want %q", id, got, err, want)
+		}
+	}
+}
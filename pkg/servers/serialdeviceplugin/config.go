@@ -0,0 +1,113 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serialdeviceplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+var resourceNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// Config is the configuration file of the serial device plugin.
+type Config struct {
+	Resources []Resource `json:"resources"`
+}
+
+// Resource describes serial devices advertised as a single resource. Patterns are glob
+// patterns, as accepted by path.Match, matched against the attributes of the USB device
+// the serial port belongs to. Empty patterns match any device.
+type Resource struct {
+	// Name of the resource, advertised to kubelet as <namespace>/<name>.
+	Name string `json:"name"`
+	// Vendor matches the USB vendor ID in hexadecimal, e.g. 0403.
+	Vendor string `json:"vendor,omitempty"`
+	// Product matches the USB product ID in hexadecimal, e.g. 6001.
+	Product string `json:"product,omitempty"`
+	// Serial matches the serial number of the USB device.
+	Serial string `json:"serial,omitempty"`
+	// ContainerPath is the path of the device node in the container, defaults to the
+	// stable path of the device, e.g. /dev/serial/by-id/usb-FTDI_FT232R_USB_UART_A50285BI-if00-port0.
+	// Further devices allocated to the same container get an index appended, e.g.
+	// /dev/plc1 for a ContainerPath of /dev/plc.
+	ContainerPath string `json:"containerPath,omitempty"`
+}
+
+// LoadConfig reads and validates the configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration describes a valid set of resources.
+func (c *Config) Validate() error {
+	if len(c.Resources) == 0 {
+		return errors.New("no resources configured")
+	}
+
+	names := map[string]struct{}{}
+	for _, r := range c.Resources {
+		if !resourceNameRegexp.MatchString(r.Name) {
+			return fmt.Errorf("invalid resource name %q", r.Name)
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("duplicate resource name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		for _, p := range []string{r.Vendor, r.Product, r.Serial} {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("resource %q: invalid pattern %q: %w", r.Name, p, err)
+			}
+		}
+		if r.ContainerPath != "" && !strings.HasPrefix(r.ContainerPath, "/") {
+			return fmt.Errorf("resource %q: container path %q is not absolute", r.Name, r.ContainerPath)
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether the resource selects the serial port.
+func (r Resource) matches(p port) bool {
+	return match(r.Vendor, p.vendor) && match(r.Product, p.product) && match(r.Serial, p.serial)
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
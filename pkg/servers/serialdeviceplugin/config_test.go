@@ -0,0 +1,88 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serialdeviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "any device",
+			config: "resources:\n- name: serial\n",
+		},
+		{
+			name:   "patterns",
+			config: "resources:\n- name: plc\n  vendor: \"0403\"\n  serial: A5*\n  containerPath: /dev/plc\n- name: serial\n",
+		},
+		{
+			name:   "no resources",
+			config: "resources: []\n",
+			err:    "no resources configured",
+		},
+		{
+			name:   "invalid name",
+			config: "resources:\n- name: PLC\n",
+			err:    "invalid resource name",
+		},
+		{
+			name:   "duplicate name",
+			config: "resources:\n- name: plc\n- name: plc\n",
+			err:    "duplicate resource name",
+		},
+		{
+			name:   "invalid pattern",
+			config: "resources:\n- name: plc\n  serial: \"[A5\"\n",
+			err:    "invalid pattern",
+		},
+		{
+			name:   "relative container path",
+			config: "resources:\n- name: plc\n  containerPath: dev/plc\n",
+			err:    "not absolute",
+		},
+		{
+			name:   "unknown field",
+			config: "resources:\n- name: plc\n  path: /dev/plc\n",
+			err:    "failed to parse config",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tc.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadConfig(file)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("LoadConfig() = %v, want nil", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("LoadConfig() = %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serialdeviceplugin
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultSysfsRoot is the mount point of sysfs on the host.
+	DefaultSysfsRoot = "/sys"
+
+	byIDDir   = "/dev/serial/by-id"
+	byPathDir = "/dev/serial/by-path"
+)
+
+// linkDirs are the directories of stable links, in order of preference.
+var linkDirs = []string{byIDDir, byPathDir}
+
+// deviceIDRegexp matches the names of stable links usable as device IDs, i.e. valid CDI
+// device names.
+var deviceIDRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+
+// port is a serial device with a stable link created by udev.
+type port struct {
+	// id is the name of the stable link, used as device ID.
+	id string
+	// link is the stable path of the device, e.g. /dev/serial/by-id/<id>.
+	link string
+	// node is the device node the link points to, e.g. /dev/ttyUSB0.
+	node string
+
+	vendor  string
+	product string
+	serial  string
+}
+
+// ports lists serial devices by their stable links in dirs, e.g. linkDirs. Links in
+// earlier directories are preferred: devices without a serial number may only be linked
+// in /dev/serial/by-path.
+func ports(dirs []string, sysfsRoot string) ([]port, error) {
+	byNode := map[string]port{}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // no serial devices (yet)
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+
+		for _, e := range entries {
+			if !deviceIDRegexp.MatchString(e.Name()) {
+				continue
+			}
+
+			link := filepath.Join(dir, e.Name())
+			node, err := filepath.EvalSymlinks(link)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue // removed in the meantime
+				}
+				return nil, fmt.Errorf("failed to resolve %s: %w", link, err)
+			}
+			if _, ok := byNode[node]; ok {
+				continue
+			}
+
+			p := port{id: e.Name(), link: link, node: node}
+			p.vendor, p.product, p.serial = usbAttributes(sysfsRoot, filepath.Base(node))
+			byNode[node] = p
+		}
+	}
+
+	list := make([]port, 0, len(byNode))
+	for _, p := range byNode {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b port) int { return cmp.Compare(a.id, b.id) })
+
+	return list, nil
+}
+
+// usbAttributes returns the vendor and product ID and the serial number of the USB device
+// the tty belongs to. Attributes are empty for ttys of other buses.
+func usbAttributes(sysfsRoot, tty string) (vendor, product, serial string) {
+	dir, err := filepath.EvalSymlinks(filepath.Join(sysfsRoot, "class", "tty", tty, "device"))
+	if err != nil {
+		return "", "", ""
+	}
+
+	// the tty device is an interface (or a port of it), the USB device is one of its parents
+	for root := filepath.Clean(sysfsRoot); dir != root && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if vendor, ok := readAttribute(dir, "idVendor"); ok {
+			product, _ := readAttribute(dir, "idProduct")
+			serial, _ := readAttribute(dir, "serial")
+			return vendor, product, serial
+		}
+	}
+	return "", "", ""
+}
+
+func readAttribute(dir, name string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
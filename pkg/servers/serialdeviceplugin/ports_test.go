@@ -0,0 +1,201 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serialdeviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fakeTree creates a /dev tree with ttys and their stable links, and a sysfs tree with
+// the USB devices they belong to. It returns the link directories and the sysfs root.
+func fakeTree(t *testing.T) ([]string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	dev := filepath.Join(root, "dev")
+	sys := filepath.Join(root, "sys")
+
+	mkdir := func(dir string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(file, data string) {
+		t.Helper()
+		mkdir(filepath.Dir(file))
+		if err := os.WriteFile(file, []byte(data+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink := func(target, link string) {
+		t.Helper()
+		mkdir(filepath.Dir(link))
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// FTDI adapter, the tty belongs to a port of the interface
+	ftdi := filepath.Join(sys, "devices", "pci0000:00", "0000:00:14.0", "usb1", "1-1")
+	write(filepath.Join(ftdi, "idVendor"), "0403")
+	write(filepath.Join(ftdi, "idProduct"), "6001")
+	write(filepath.Join(ftdi, "serial"), "A50285BI")
+	mkdir(filepath.Join(ftdi, "1-1:1.0", "ttyUSB0"))
+	symlink(filepath.Join(ftdi, "1-1:1.0", "ttyUSB0"), filepath.Join(sys, "class", "tty", "ttyUSB0", "device"))
+
+	// CDC ACM device without a serial number, the tty belongs to the interface
+	acm := filepath.Join(sys, "devices", "pci0000:00", "0000:00:14.0", "usb1", "1-2")
+	write(filepath.Join(acm, "idVendor"), "2341")
+	write(filepath.Join(acm, "idProduct"), "0043")
+	mkdir(filepath.Join(acm, "1-2:1.0"))
+	symlink(filepath.Join(acm, "1-2:1.0"), filepath.Join(sys, "class", "tty", "ttyACM0", "device"))
+
+	// on-board UART
+	uart := filepath.Join(sys, "devices", "platform", "serial8250")
+	mkdir(uart)
+	symlink(uart, filepath.Join(sys, "class", "tty", "ttyS0", "device"))
+
+	for _, tty := range []string{"ttyUSB0", "ttyACM0", "ttyS0"} {
+		write(filepath.Join(dev, tty), "")
+	}
+
+	byID := filepath.Join(dev, "serial", "by-id")
+	byPath := filepath.Join(dev, "serial", "by-path")
+	symlink("../../ttyUSB0", filepath.Join(byID, "usb-FTDI_FT232R_USB_UART_A50285BI-if00-port0"))
+	symlink("../../ttyUSB0", filepath.Join(byPath, "pci-0000:00:14.0-usb-0:1:1.0-port0"))
+	symlink("../../ttyACM0", filepath.Join(byPath, "pci-0000:00:14.0-usb-0:2:1.0"))
+	symlink("../../ttyS0", filepath.Join(byPath, "platform-serial8250-serial0"))
+	// links which are not valid device IDs, or whose tty is gone, are ignored
+	symlink("../../ttyS0", filepath.Join(byPath, "with space"))
+	symlink("../../ttyUSB1", filepath.Join(byID, "usb-FTDI_FT232R_USB_UART_A50285BJ-if00-port0"))
+
+	return []string{byID, byPath}, sys
+}
+
+func TestPorts(t *testing.T) {
+	t.Parallel()
+
+	dirs, sys := fakeTree(t)
+	list, err := ports(dirs, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []port{
+		{
+			id:   "pci-0000:00:14.0-usb-0:2:1.0",
+			link: filepath.Join(dirs[1], "pci-0000:00:14.0-usb-0:2:1.0"),
+			node: "ttyACM0", vendor: "2341", product: "0043",
+		},
+		{
+			id:   "platform-serial8250-serial0",
+			link: filepath.Join(dirs[1], "platform-serial8250-serial0"),
+			node: "ttyS0",
+		},
+		{
+			// by-id links are preferred over by-path links of the same tty
+			id:   "usb-FTDI_FT232R_USB_UART_A50285BI-if00-port0",
+			link: filepath.Join(dirs[0], "usb-FTDI_FT232R_USB_UART_A50285BI-if00-port0"),
+			node: "ttyUSB0", vendor: "0403", product: "6001", serial: "A50285BI",
+		},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("ports() = %+v, want %+v", list, want)
+	}
+	for i, p := range list {
+		w := want[i]
+		w.node = filepath.Join(filepath.Dir(filepath.Dir(dirs[0])), w.node)
+		if p != w {
+			t.Errorf("ports()[%d] = %+v, want %+v", i, p, w)
+		}
+	}
+}
+
+func TestPortsWithoutLinks(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	list, err := ports([]string{filepath.Join(root, "by-id"), filepath.Join(root, "by-path")}, root)
+	if err != nil || len(list) != 0 {
+		t.Errorf("ports() = %v, %v, want no ports", list, err)
+	}
+}
+
+func TestUSBAttributes(t *testing.T) {
+	t.Parallel()
+
+	_, sys := fakeTree(t)
+	for tty, want := range map[string][3]string{
+		"ttyUSB0": {"0403", "6001", "A50285BI"},
+		"ttyACM0": {"2341", "0043", ""},
+		"ttyS0":   {"", "", ""},
+		"ttyS1":   {"", "", ""},
+	} {
+		vendor, product, serial := usbAttributes(sys, tty)
+		if got := [3]string{vendor, product, serial}; got != want {
+			t.Errorf("usbAttributes(%s) = %q, want %q", tty, got, want)
+		}
+	}
+}
+
+func TestResourceMatches(t *testing.T) {
+	t.Parallel()
+
+	dirs, sys := fakeTree(t)
+	list, err := ports(dirs, sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		resource Resource
+		want     []string
+	}{
+		{
+			resource: Resource{Name: "any"},
+			want:     []string{"ttyACM0", "ttyS0", "ttyUSB0"},
+		},
+		{
+			resource: Resource{Name: "ftdi", Vendor: "0403", Product: "6001"},
+			want:     []string{"ttyUSB0"},
+		},
+		{
+			resource: Resource{Name: "serial", Serial: "A5*"},
+			want:     []string{"ttyUSB0"},
+		},
+		{
+			resource: Resource{Name: "arduino", Vendor: "2341", Product: "00[4-5]?"},
+			want:     []string{"ttyACM0"},
+		},
+		{
+			resource: Resource{Name: "other", Vendor: "0403", Serial: "B*"},
+			want:     []string{},
+		},
+	} {
+		got := []string{}
+		for _, p := range list {
+			if tc.resource.matches(p) {
+				got = append(got, filepath.Base(p.node))
+			}
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s matches %v, want %v", tc.resource.Name, got, tc.want)
+		}
+	}
+}
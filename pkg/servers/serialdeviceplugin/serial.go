@@ -0,0 +1,288 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serialdeviceplugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	serialName = "serial"
+	rwPerm     = "rw"
+)
+
+// DefaultHealthChecker verifies that the device node is a character device. Serial
+// devices are not opened, as opening a port may reset the attached equipment.
+var DefaultHealthChecker = healthcheck.AnyCharDevice()
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	namespace string
+	resource  Resource
+	before    []Resource
+	sysfsRoot string
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+
+	mu    sync.RWMutex
+	ports map[string]port
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// Servers creates a server for every resource of the configuration. Serial devices are
+// advertised by the first resource matching them.
+func Servers(
+	namespace string,
+	cfg *Config,
+	sysfsRoot string,
+	cdiConfig cdi.Config,
+	log *slog.Logger,
+) []*Server {
+	servers := make([]*Server, 0, len(cfg.Resources))
+	for i, r := range cfg.Resources {
+		servers = append(servers, New(namespace, r, cfg.Resources[:i], sysfsRoot, cdiConfig, nil, log))
+	}
+	return servers
+}
+
+// New creates the serial device plugin server of the resource, advertising every serial
+// device matching it but none of the resources before it. If checker is nil,
+// DefaultHealthChecker is used.
+func New(
+	namespace string,
+	resource Resource,
+	before []Resource,
+	sysfsRoot string,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	if sysfsRoot == "" {
+		sysfsRoot = DefaultSysfsRoot
+	}
+	s := &Server{
+		log:       log.With("resource", resource.Name),
+		namespace: namespace,
+		resource:  resource,
+		before:    before,
+		sysfsRoot: sysfsRoot,
+		cdi:       cdiConfig,
+		checker:   checker,
+		ports:     map[string]port{},
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		s.log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		s.log.Warn("No serial device found")
+	}
+	return s
+}
+
+// Discover lists serial devices matching the resource and rebuilds the list of advertised
+// devices. Changes are published to kubelet on the next call to Update.
+func (s *Server) Discover() error {
+	list, err := ports(linkDirs, s.sysfsRoot)
+	if err != nil {
+		return err
+	}
+
+	devs := []*v1beta1.Device{}
+	matched := map[string]port{}
+	for _, p := range list {
+		if !s.resource.matches(p) || slices.ContainsFunc(s.before, func(r Resource) bool { return r.matches(p) }) {
+			continue
+		}
+
+		health := v1beta1.Healthy
+		if herr := s.checker.Check(p.node); herr != nil {
+			health = v1beta1.Unhealthy
+			if !s.wasUnhealthy(p.id) {
+				reason := healthcheck.Reason(herr)
+				s.log.Warn("Serial device is unhealthy", "device", p.id, "reason", reason, "error", herr)
+				metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+			}
+		}
+
+		devs = append(devs, &v1beta1.Device{
+			ID:     p.id,
+			Health: health,
+		})
+		matched[p.id] = p
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// devices are compared by their nodes as well, which change when they are plugged again
+	if plugin.EqualDevices(devs, s.Devices()) && maps.Equal(matched, s.ports) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("Serial devices disappeared")
+	case len(devs) != len(s.Devices()):
+		s.log.Info("Discovered serial devices", "devices", len(devs))
+	}
+
+	s.ports = matched
+	s.SetDevices(devs)
+	return nil
+}
+
+// wasUnhealthy reports whether the device was advertised as unhealthy before.
+func (s *Server) wasUnhealthy(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devs := s.Devices()
+	i := slices.IndexFunc(devs, func(dev *v1beta1.Device) bool { return dev.ID == id })
+	return i >= 0 && devs[i].Health == v1beta1.Unhealthy
+}
+
+// containerPath returns the path of the i-th device allocated to a container.
+func (s *Server) containerPath(p port, i int) string {
+	switch {
+	case s.resource.ContainerPath == "":
+		return p.link
+	case i == 0:
+		return s.resource.ContainerPath
+	default:
+		return s.resource.ContainerPath + strconv.Itoa(i)
+	}
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		p := s.ports[dev.ID]
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name: dev.ID,
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        s.containerPath(p, 0),
+						HostPath:    p.node,
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the stable link patterns watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	return []string{path.Join(byIDDir, "*"), path.Join(byPathDir, "*")}
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, s.resource.Name)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, serialName+"-"+s.resource.Name+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		// CDI specs place every device at the container path, as the index of a device
+		// within the allocation is not known in advance
+		if s.cdi.Mode.CDI() && s.resource.ContainerPath != "" && len(creq.DevicesIDs) > 1 {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"devices of %s are placed at %s, only a single device can be allocated to a container",
+				s.Name(), s.resource.ContainerPath)
+		}
+
+		cres := &v1beta1.ContainerAllocateResponse{}
+		if s.cdi.Mode.DeviceSpec() {
+			for i, id := range creq.DevicesIDs {
+				p, ok := s.ports[id]
+				if !ok {
+					return nil, status.Errorf(codes.NotFound, "unknown serial device %q", id)
+				}
+				cres.Devices = append(cres.Devices, &v1beta1.DeviceSpec{
+					ContainerPath: s.containerPath(p, i),
+					HostPath:      p.node,
+					Permissions:   rwPerm,
+				})
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tundeviceplugin
+
+import (
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+)
+
+var _ features.Source = (*Server)(nil)
+
+// GetFeaturesFunc returns the TUN/TAP flags supported by the kernel, as reported by
+// TUNGETFEATURES on the TUN device at path.
+type GetFeaturesFunc func(path string) (uint32, error)
+
+var _ GetFeaturesFunc = GetFeatures
+
+// GetFeatures opens the TUN device at path and issues TUNGETFEATURES.
+func GetFeatures(path string) (uint32, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer unix.Close(fd) //nolint:errcheck // best effort call
+
+	return unix.IoctlGetUint32(fd, unix.TUNGETFEATURES)
+}
+
+// Features probes the TUN/TAP flags supported by the kernel, using TUNGETFEATURES.
+// No features are reported if /dev/net/tun can not be probed.
+func (s *Server) Features() map[string]string {
+	flags, err := s.getFeatures(s.path)
+	if err != nil {
+		s.log.Debug("Failed to probe TUN features", "error", err)
+		return map[string]string{}
+	}
+
+	return map[string]string{
+		"tun.multiqueue": features.Bool(flags&unix.IFF_MULTI_QUEUE != 0),
+		"tun.vnet-hdr":   features.Bool(flags&unix.IFF_VNET_HDR != 0),
+	}
+}
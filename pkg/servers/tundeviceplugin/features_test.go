@@ -0,0 +1,113 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tundeviceplugin
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+)
+
+func TestFeatures(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		flags uint32
+		err   error
+		want  map[string]string
+	}{
+		{
+			name:  "all",
+			flags: unix.IFF_TUN | unix.IFF_TAP | unix.IFF_MULTI_QUEUE | unix.IFF_VNET_HDR,
+			want:  map[string]string{"tun.multiqueue": "true", "tun.vnet-hdr": "true"},
+		},
+		{
+			name:  "multiqueue",
+			flags: unix.IFF_TUN | unix.IFF_MULTI_QUEUE,
+			want:  map[string]string{"tun.multiqueue": "true", "tun.vnet-hdr": "false"},
+		},
+		{
+			name:  "none",
+			flags: unix.IFF_TUN,
+			want:  map[string]string{"tun.multiqueue": "false", "tun.vnet-hdr": "false"},
+		},
+		{
+			name: "ioctl failure",
+			err:  unix.EINVAL,
+			want: map[string]string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newServer(filepath.Join(t.TempDir(), "tun"), "devices.anza-labs.dev", 1,
+				cdi.Config{Mode: cdi.ModeDeviceSpec}, healthy, nil)
+			t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+			var probed string
+			s.getFeatures = func(path string) (uint32, error) {
+				probed = path
+				return tc.flags, tc.err
+			}
+
+			if got := s.Features(); !maps.Equal(got, tc.want) {
+				t.Errorf("Features() = %v, want %v", got, tc.want)
+			}
+			if probed != s.path {
+				t.Errorf("probed %q, want %q", probed, s.path)
+			}
+		})
+	}
+}
+
+func TestUpdateFeatures(t *testing.T) {
+	t.Parallel()
+
+	s := newServer(filepath.Join(t.TempDir(), "tun"), "devices.anza-labs.dev", 1,
+		cdi.Config{Mode: cdi.ModeDeviceSpec}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	var probeErr error
+	s.getFeatures = func(string) (uint32, error) {
+		return unix.IFF_TUN | unix.IFF_VNET_HDR, probeErr
+	}
+
+	f := features.NewFile(t.TempDir(), s.Name())
+	s.SetFeatureFile(f)
+
+	data, err := os.ReadFile(f.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "devices.anza-labs.dev/tun.multiqueue=false\ndevices.anza-labs.dev/tun.vnet-hdr=true\n"
+	if string(data) != want {
+		t.Errorf("feature file = %q, want %q", data, want)
+	}
+
+	// the file is removed once the device can no longer be probed
+	probeErr = errors.New("no device")
+	s.Update()
+	if _, err := os.Stat(f.Path()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("feature file was not removed: %v", err)
+	}
+}
@@ -15,11 +15,16 @@
 package tundeviceplugin
 
 import (
-	"context"
-	"fmt"
 	"log/slog"
-	"os"
-	"path"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/features"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
 
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
@@ -27,113 +32,101 @@ import (
 const (
 	tunPath = "/dev/net/tun"
 	tunName = "tun"
-	rwPerm  = "rw"
+
+	tunMajor = 10
+	tunMinor = 200
+)
+
+// DefaultHealthChecker verifies that /dev/net/tun is the TUN/TAP misc device and that
+// it can be opened.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.CharDevice(tunMajor, tunMinor),
+	healthcheck.Open(unix.O_RDWR),
 )
 
 type Server struct {
-	log       *slog.Logger
-	namespace string
-	update    chan struct{}
-	devices   uint
-	devs      []*v1beta1.Device
+	*plugin.NodeServer
+
+	log         *slog.Logger
+	path        string
+	getFeatures GetFeaturesFunc
+	features    *features.File
+
+	mu sync.Mutex
 }
 
-var _ v1beta1.DevicePluginServer = (*Server)(nil)
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the TUN device plugin server. If checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	devices uint,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	return newServer(tunPath, namespace, devices, cdiConfig, checker, log)
+}
 
-func New(namespace string, devices uint, log *slog.Logger) *Server {
+func newServer(
+	devPath string,
+	namespace string,
+	devices uint,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
 	if log == nil {
 		log = slog.New(slog.DiscardHandler)
 	}
-	s := &Server{
-		log:       log,
-		namespace: namespace,
-		devices:   devices,
-		update:    make(chan struct{}),
-		devs:      []*v1beta1.Device{},
+	if checker == nil {
+		checker = DefaultHealthChecker
 	}
-	s.discover()
-	return s
-}
-
-func (s *Server) discover() {
-	if _, err := os.Stat(tunPath); err == nil {
-		s.log.Debug("Discovered TUN device")
-
-		for i := uint(0); i < s.devices; i++ {
-			s.devs = append(s.devs, &v1beta1.Device{
-				ID:     fmt.Sprintf("%s%d", tunName, i),
-				Health: v1beta1.Healthy,
-			})
-		}
-	} else {
-		s.log.Error("No TUN device found")
+	return &Server{
+		NodeServer: plugin.NewNodeServer(plugin.NodeConfig{
+			Namespace: namespace,
+			Name:      tunName,
+			Kind:      "TUN",
+			Path:      devPath,
+			Devices:   devices,
+			Checker:   checker,
+		}, cdiConfig, log),
+		log:         log,
+		path:        devPath,
+		getFeatures: GetFeatures,
 	}
 }
 
-func (s *Server) Name() string {
-	return path.Join(s.namespace, tunName)
-}
+// Update publishes the device list to ListAndWatch streams if it changed since the last
+// call and refreshes the feature file.
+func (s *Server) Update() {
+	s.NodeServer.Update()
 
-func (s *Server) Socket() string {
-	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, tunName+".sock"))
-}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-func (s *Server) GetDevicePluginOptions(
-	ctx context.Context,
-	_ *v1beta1.Empty,
-) (*v1beta1.DevicePluginOptions, error) {
-	return &v1beta1.DevicePluginOptions{
-		PreStartRequired:                false,
-		GetPreferredAllocationAvailable: false,
-	}, nil
+	s.updateFeatures()
 }
 
-func (s *Server) ListAndWatch(
-	_ *v1beta1.Empty,
-	lws v1beta1.DevicePlugin_ListAndWatchServer,
-) error {
-	if err := lws.Send(&v1beta1.ListAndWatchResponse{Devices: s.devs}); err != nil {
-		s.log.Error("Failed to send ListAndWatch response", "error", err)
-	}
-
-	for range s.update {
-		if err := lws.Send(&v1beta1.ListAndWatchResponse{Devices: s.devs}); err != nil {
-			s.log.Error("Failed to send ListAndWatch response", "error", err)
-		}
-	}
+// SetFeatureFile enables writing device features to the feature file, which is
+// refreshed on every update.
+func (s *Server) SetFeatureFile(f *features.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	panic("unexpected error")
+	s.features = f
+	s.updateFeatures()
 }
 
-func (s *Server) Allocate(
-	ctx context.Context,
-	req *v1beta1.AllocateRequest,
-) (*v1beta1.AllocateResponse, error) {
-	s.update <- struct{}{}
-
-	devices := []*v1beta1.DeviceSpec{
-		{
-			ContainerPath: tunPath,
-			HostPath:      tunPath,
-			Permissions:   rwPerm,
-		},
+func (s *Server) updateFeatures() {
+	if s.features == nil {
+		return
+	}
+	if err := s.features.Write(s.Features()); err != nil {
+		s.log.Error("Failed to update feature file", "path", s.features.Path(), "error", err)
 	}
-
-	return &v1beta1.AllocateResponse{
-		ContainerResponses: []*v1beta1.ContainerAllocateResponse{{Devices: devices}},
-	}, nil
-}
-
-func (s *Server) GetPreferredAllocation(
-	ctx context.Context,
-	req *v1beta1.PreferredAllocationRequest,
-) (*v1beta1.PreferredAllocationResponse, error) {
-	return &v1beta1.PreferredAllocationResponse{}, nil
-}
-
-func (s *Server) PreStartContainer(
-	ctx context.Context,
-	req *v1beta1.PreStartContainerRequest,
-) (*v1beta1.PreStartContainerResponse, error) {
-	return &v1beta1.PreStartContainerResponse{}, nil
 }
@@ -0,0 +1,67 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tundeviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthy accepts any device node, as device nodes can not be created in tests.
+var healthy = healthcheck.CheckerFunc(func(string) error { return nil })
+
+func TestDiscoverUpdate(t *testing.T) {
+	t.Parallel()
+
+	devPath := filepath.Join(t.TempDir(), "tun")
+	s := newServer(devPath, "devices.anza-labs.dev", 2, cdi.Config{Mode: cdi.ModeDeviceSpec}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	if devs := s.Devices(); len(devs) != 0 {
+		t.Fatalf("Devices() = %v without device node, want empty", devs)
+	}
+
+	if err := os.WriteFile(devPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Discover(); err != nil {
+		t.Fatal(err)
+	}
+
+	devs := s.Devices()
+	if len(devs) != 2 {
+		t.Fatalf("Devices() = %v, want 2 devices", devs)
+	}
+	for _, dev := range devs {
+		if dev.Health != v1beta1.Healthy {
+			t.Errorf("health of %s = %s, want %s", dev.ID, dev.Health, v1beta1.Healthy)
+		}
+	}
+
+	// discovered devices are only published to kubelet on update
+	if got := s.Advertised(); len(got) != 0 {
+		t.Errorf("Advertised() = %v before update, want empty", got)
+	}
+	s.Update()
+	if got := s.Advertised(); len(got) != 2 {
+		t.Errorf("Advertised() = %v after update, want 2 devices", got)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfiodeviceplugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var resourceNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// ID is the vendor and device ID of a PCI device, e.g. 8086:1572.
+type ID struct {
+	Vendor uint16
+	Device uint16
+}
+
+// ParseID parses a PCI ID in the <vendor>:<device> form, both in hexadecimal.
+func ParseID(s string) (ID, error) {
+	vendor, device, ok := strings.Cut(s, ":")
+	if !ok {
+		return ID{}, fmt.Errorf("invalid PCI ID %q, expected <vendor>:<device>", s)
+	}
+
+	v, err := strconv.ParseUint(strings.TrimPrefix(vendor, "0x"), 16, 16)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid vendor ID in %q: %w", s, err)
+	}
+	d, err := strconv.ParseUint(strings.TrimPrefix(device, "0x"), 16, 16)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid device ID in %q: %w", s, err)
+	}
+
+	return ID{Vendor: uint16(v), Device: uint16(d)}, nil
+}
+
+func (id ID) String() string {
+	return fmt.Sprintf("%04x:%04x", id.Vendor, id.Device)
+}
+
+// resourceName returns the default name of the resource of devices with the ID.
+func (id ID) resourceName() string {
+	return fmt.Sprintf("pci-%04x-%04x", id.Vendor, id.Device)
+}
+
+// Aliases maps PCI IDs to names of the resources their devices are advertised as.
+// Several IDs may share a name, e.g. for different revisions of a NIC.
+type Aliases map[ID]string
+
+// ParseAliases parses values of the --alias flag in the <vendor>:<device>=<name> form.
+func ParseAliases(values []string) (Aliases, error) {
+	aliases := Aliases{}
+	for _, v := range values {
+		s, name, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid alias %q, expected <vendor>:<device>=<name>", v)
+		}
+
+		id, err := ParseID(s)
+		if err != nil {
+			return nil, err
+		}
+		if !resourceNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid resource name %q of alias %q", name, v)
+		}
+		if prev, ok := aliases[id]; ok && prev != name {
+			return nil, fmt.Errorf("conflicting aliases %q and %q of %s", prev, name, id)
+		}
+
+		aliases[id] = name
+	}
+	return aliases, nil
+}
+
+// name returns the resource name of devices with the ID.
+func (a Aliases) name(id ID) string {
+	if name, ok := a[id]; ok {
+		return name
+	}
+	return id.resourceName()
+}
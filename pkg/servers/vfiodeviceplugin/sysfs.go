@@ -0,0 +1,172 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfiodeviceplugin
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultSysfsRoot is the mount point of sysfs on the host.
+	DefaultSysfsRoot = "/sys"
+
+	vfioDriver = "vfio-pci"
+)
+
+// viableDrivers are the drivers which devices of an IOMMU group may be bound to, besides
+// vfio-pci, for the group to be usable by VFIO.
+var viableDrivers = []string{"", vfioDriver, "pci-stub", "pcieport"}
+
+// pciDevice is a PCI device in an IOMMU group.
+type pciDevice struct {
+	address string
+	id      ID
+	driver  string
+}
+
+// group is an IOMMU group, the unit devices are passed through to VMs in.
+type group struct {
+	number  uint
+	devices []pciDevice
+}
+
+// scan lists all IOMMU groups in the sysfs tree.
+func scan(root string) ([]group, error) {
+	dir := filepath.Join(root, "kernel", "iommu_groups")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil // IOMMU is disabled
+		}
+		return nil, fmt.Errorf("failed to list IOMMU groups: %w", err)
+	}
+
+	groups := make([]group, 0, len(entries))
+	for _, e := range entries {
+		n, err := strconv.ParseUint(e.Name(), 10, 0)
+		if err != nil {
+			continue
+		}
+
+		devices, err := scanGroup(filepath.Join(dir, e.Name(), "devices"))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group{number: uint(n), devices: devices})
+	}
+	slices.SortFunc(groups, func(a, b group) int { return cmp.Compare(a.number, b.number) })
+
+	return groups, nil
+}
+
+// scanGroup reads the PCI devices of the IOMMU group, sorted by address.
+func scanGroup(dir string) ([]pciDevice, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices of IOMMU group: %w", err)
+	}
+
+	devices := make([]pciDevice, 0, len(entries))
+	for _, e := range entries {
+		dev, err := readDevice(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, dev)
+	}
+	slices.SortFunc(devices, func(a, b pciDevice) int { return cmp.Compare(a.address, b.address) })
+
+	return devices, nil
+}
+
+// readDevice reads the IDs and the driver of the PCI device at path.
+func readDevice(path string) (pciDevice, error) {
+	dev := pciDevice{address: filepath.Base(path)}
+
+	vendor, err := readHex(filepath.Join(path, "vendor"))
+	if err != nil {
+		return dev, err
+	}
+	device, err := readHex(filepath.Join(path, "device"))
+	if err != nil {
+		return dev, err
+	}
+	dev.id = ID{Vendor: vendor, Device: device}
+
+	driver, err := os.Readlink(filepath.Join(path, "driver"))
+	switch {
+	case err == nil:
+		dev.driver = filepath.Base(driver)
+	case errors.Is(err, fs.ErrNotExist):
+		// device is not bound to any driver
+	default:
+		return dev, fmt.Errorf("failed to read driver of %s: %w", dev.address, err)
+	}
+
+	return dev, nil
+}
+
+func readHex(file string) (uint16, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return uint16(v), nil
+}
+
+// id returns the ID of the first device of the group bound to vfio-pci, which decides
+// the resource the group is advertised as.
+func (g group) id() (ID, bool) {
+	for _, dev := range g.devices {
+		if dev.driver == vfioDriver {
+			return dev.id, true
+		}
+	}
+	return ID{}, false
+}
+
+// addresses returns the PCI addresses of the devices bound to vfio-pci.
+func (g group) addresses() []string {
+	addrs := []string{}
+	for _, dev := range g.devices {
+		if dev.driver == vfioDriver {
+			addrs = append(addrs, dev.address)
+		}
+	}
+	return addrs
+}
+
+// viable returns an error if a device of the group is bound to a host driver, in which
+// case VFIO refuses to open the group.
+func (g group) viable() error {
+	for _, dev := range g.devices {
+		if !slices.Contains(viableDrivers, dev.driver) {
+			return fmt.Errorf("device %s of IOMMU group %d is bound to %s", dev.address, g.number, dev.driver)
+		}
+	}
+	return nil
+}
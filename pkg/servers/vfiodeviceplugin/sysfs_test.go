@@ -0,0 +1,134 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfiodeviceplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type fakePCI struct {
+	address string
+	id      ID
+	driver  string
+}
+
+// fakeSysfs creates a sysfs tree with the PCI devices in the IOMMU groups.
+func fakeSysfs(t *testing.T, groups map[uint][]fakePCI) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for n, devs := range groups {
+		dir := filepath.Join(root, "kernel", "iommu_groups", fmt.Sprint(n), "devices")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, dev := range devs {
+			path := filepath.Join(dir, dev.address)
+			if err := os.Mkdir(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for file, v := range map[string]uint16{"vendor": dev.id.Vendor, "device": dev.id.Device} {
+				if err := os.WriteFile(filepath.Join(path, file), fmt.Appendf(nil, "0x%04x\n", v), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if dev.driver == "" {
+				continue
+			}
+			if err := os.Symlink("../../bus/pci/drivers/"+dev.driver, filepath.Join(path, "driver")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+var (
+	x710   = ID{Vendor: 0x8086, Device: 0x1572}
+	bridge = ID{Vendor: 0x8086, Device: 0x1901}
+)
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	root := fakeSysfs(t, map[uint][]fakePCI{
+		12: {
+			{address: "0000:03:00.1", id: x710, driver: vfioDriver},
+			{address: "0000:03:00.0", id: x710, driver: vfioDriver},
+		},
+		2: {
+			{address: "0000:00:01.0", id: bridge, driver: "pcieport"},
+			{address: "0000:01:00.0", id: x710, driver: "i40e"},
+		},
+	})
+	// entries other than group numbers are ignored
+	if err := os.Mkdir(filepath.Join(root, "kernel", "iommu_groups", "other"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].number != 2 || groups[1].number != 12 {
+		t.Fatalf("scan() = %+v, want groups 2 and 12", groups)
+	}
+
+	if id, ok := groups[0].id(); ok {
+		t.Errorf("id() of a group without vfio-pci devices = %v, want none", id)
+	}
+	if err := groups[0].viable(); err == nil {
+		t.Error("viable() of a group with a device bound to i40e = nil, want error")
+	}
+
+	if id, ok := groups[1].id(); !ok || id != x710 {
+		t.Errorf("id() = %v, %v, want %v", id, ok, x710)
+	}
+	if err := groups[1].viable(); err != nil {
+		t.Errorf("viable() = %v, want nil", err)
+	}
+	want := []string{"0000:03:00.0", "0000:03:00.1"}
+	if got := groups[1].addresses(); !slices.Equal(got, want) {
+		t.Errorf("addresses() = %v, want %v", got, want)
+	}
+}
+
+func TestScanWithoutIOMMU(t *testing.T) {
+	t.Parallel()
+
+	groups, err := scan(t.TempDir())
+	if err != nil || groups != nil {
+		t.Errorf("scan() = %v, %v, want no groups", groups, err)
+	}
+}
+
+func TestScanInvalidDevice(t *testing.T) {
+	t.Parallel()
+
+	root := fakeSysfs(t, map[uint][]fakePCI{1: {{address: "0000:03:00.0", id: x710, driver: vfioDriver}}})
+	file := filepath.Join(root, "kernel", "iommu_groups", "1", "devices", "0000:03:00.0", "vendor")
+	if err := os.WriteFile(file, []byte("invalid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := scan(root); err == nil {
+		t.Error("scan() = nil, want error")
+	}
+}
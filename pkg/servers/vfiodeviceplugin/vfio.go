@@ -0,0 +1,382 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfiodeviceplugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	// DefaultDevRoot is the mount point of the host /dev.
+	DefaultDevRoot = "/dev"
+
+	vfioDir  = "/dev/vfio"
+	vfioPath = vfioDir + "/vfio"
+	vfioName = "vfio"
+	rwPerm   = "rw"
+
+	vfioMajor = 10
+	vfioMinor = 196
+
+	// EnvPrefix is the prefix of the environment variable listing the PCI addresses of
+	// the allocated devices, as expected by KubeVirt.
+	EnvPrefix = "PCI_RESOURCE"
+
+	reasonNotViable = "group_not_viable"
+)
+
+// DefaultHealthChecker verifies that the VFIO container node, vfio/vfio, is the expected
+// character device and that IOMMU group nodes are character devices. Group nodes are not
+// opened, as VFIO allows a single open file of a group at a time.
+var DefaultHealthChecker = healthcheck.CheckerFunc(func(path string) error {
+	if filepath.Base(path) == vfioName {
+		return containerChecker.Check(path)
+	}
+	return groupChecker.Check(path)
+})
+
+var (
+	containerChecker = healthcheck.CharDevice(vfioMajor, vfioMinor)
+	groupChecker     = healthcheck.AnyCharDevice()
+)
+
+// EnvName returns the name of the environment variable listing the PCI addresses of the
+// devices of the resource allocated to a container, e.g. PCI_RESOURCE_DEVICES_ANZA-LABS_DEV_X710.
+func EnvName(resource string) string {
+	return EnvPrefix + "_" + strings.NewReplacer("/", "_", ".", "_").Replace(strings.ToUpper(resource))
+}
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	namespace string
+	name      string
+	ids       []ID
+	sysfsRoot string
+	devRoot   string
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+
+	mu        sync.RWMutex
+	addresses map[string][]string
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// Servers creates a server for every resource, i.e. for every name in aliases, and for
+// the ID of every IOMMU group bound to vfio-pci in the sysfs tree at sysfsRoot which has
+// no alias. Groups bound to vfio-pci later are only advertised if their ID has an alias.
+// Device nodes are checked below devRoot, see New.
+func Servers(
+	namespace string,
+	sysfsRoot string,
+	devRoot string,
+	aliases Aliases,
+	cdiConfig cdi.Config,
+	log *slog.Logger,
+) ([]*Server, error) {
+	groups, err := scan(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := map[string][]ID{}
+	for id, name := range aliases {
+		resources[name] = append(resources[name], id)
+	}
+	for _, g := range groups {
+		id, ok := g.id()
+		if !ok {
+			continue
+		}
+		if name := aliases.name(id); !slices.Contains(resources[name], id) {
+			resources[name] = append(resources[name], id)
+		}
+	}
+
+	servers := make([]*Server, 0, len(resources))
+	for _, name := range slices.Sorted(maps.Keys(resources)) {
+		servers = append(servers, New(namespace, name, resources[name], sysfsRoot, devRoot, cdiConfig, nil, log))
+	}
+	return servers, nil
+}
+
+// New creates the VFIO device plugin server of the named resource, advertising every
+// IOMMU group whose first device bound to vfio-pci has one of the IDs. The checker is run
+// against the VFIO container node and the group nodes below devRoot, where the host /dev
+// is mounted, while containers always get the host paths. If checker is nil,
+// DefaultHealthChecker is used.
+func New(
+	namespace string,
+	name string,
+	ids []ID,
+	sysfsRoot string,
+	devRoot string,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	if sysfsRoot == "" {
+		sysfsRoot = DefaultSysfsRoot
+	}
+	if devRoot == "" {
+		devRoot = DefaultDevRoot
+	}
+	s := &Server{
+		log:       log.With("resource", name),
+		namespace: namespace,
+		name:      name,
+		ids:       ids,
+		sysfsRoot: sysfsRoot,
+		devRoot:   devRoot,
+		cdi:       cdiConfig,
+		checker:   checker,
+		addresses: map[string][]string{},
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		s.log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		s.log.Warn("No IOMMU group found", "ids", ids)
+	}
+	return s
+}
+
+// Discover lists the IOMMU groups of the resource and rebuilds the list of advertised
+// devices. Changes are published to kubelet on the next call to Update.
+func (s *Server) Discover() error {
+	groups, err := scan(s.sysfsRoot)
+	if err != nil {
+		return err
+	}
+
+	cerr := s.checker.Check(s.devPath(vfioPath))
+
+	devs := []*v1beta1.Device{}
+	addresses := map[string][]string{}
+	for _, g := range groups {
+		if pciID, ok := g.id(); !ok || !slices.Contains(s.ids, pciID) {
+			continue
+		}
+
+		id := deviceID(g.number)
+		health := v1beta1.Healthy
+		if herr := s.check(g, cerr); herr != nil {
+			health = v1beta1.Unhealthy
+			if !s.wasUnhealthy(id) {
+				reason := healthcheck.Reason(herr)
+				s.log.Warn("IOMMU group is unhealthy", "group", id, "reason", reason, "error", herr)
+				metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+			}
+		}
+
+		devs = append(devs, &v1beta1.Device{
+			ID:     id,
+			Health: health,
+		})
+		addresses[id] = g.addresses()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if plugin.EqualDevices(devs, s.Devices()) && maps.EqualFunc(addresses, s.addresses, slices.Equal) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("IOMMU groups disappeared")
+	case len(devs) != len(s.Devices()):
+		s.log.Info("Discovered IOMMU groups", "devices", len(devs))
+	}
+
+	s.addresses = addresses
+	s.SetDevices(devs)
+	return nil
+}
+
+// check returns an error if the VFIO container node is unusable, or if the IOMMU group
+// can not be opened.
+func (s *Server) check(g group, cerr error) error {
+	if cerr != nil {
+		return cerr
+	}
+	if err := g.viable(); err != nil {
+		return &healthcheck.Error{Reason: reasonNotViable, Err: err}
+	}
+	return s.checker.Check(s.devPath(groupPath(deviceID(g.number))))
+}
+
+// devPath returns the path of the host device node p as seen by the plugin.
+func (s *Server) devPath(p string) string {
+	return filepath.Join(s.devRoot, strings.TrimPrefix(p, DefaultDevRoot))
+}
+
+// wasUnhealthy reports whether the device was advertised as unhealthy before.
+func (s *Server) wasUnhealthy(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devs := s.Devices()
+	i := slices.IndexFunc(devs, func(dev *v1beta1.Device) bool { return dev.ID == id })
+	return i >= 0 && devs[i].Health == v1beta1.Unhealthy
+}
+
+// deviceID returns the device ID of an IOMMU group, which is the group number.
+func deviceID(group uint) string {
+	return strconv.FormatUint(uint64(group), 10)
+}
+
+func groupPath(id string) string {
+	return path.Join(vfioDir, id)
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name: dev.ID,
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        vfioPath,
+						HostPath:    vfioPath,
+						Permissions: rwPerm,
+					},
+					{
+						Path:        groupPath(dev.ID),
+						HostPath:    groupPath(dev.ID),
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the device node patterns watched for changes by discovery. Group
+// nodes are created once a device of the group is bound to vfio-pci.
+func (s *Server) WatchPaths() []string {
+	return []string{s.devPath(path.Join(vfioDir, "*"))}
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, s.name)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, vfioName+"-"+s.name+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	for _, creq := range req.ContainerRequests {
+		addrs := []string{}
+		for _, id := range creq.DevicesIDs {
+			a, ok := s.addresses[id]
+			if !ok {
+				return nil, status.Errorf(codes.NotFound, "unknown IOMMU group %q", id)
+			}
+			addrs = append(addrs, a...)
+		}
+
+		// addresses are passed as environment variable in all device modes, as CDI specs
+		// of multiple devices can not set different values of the same variable
+		cres := &v1beta1.ContainerAllocateResponse{
+			Envs: map[string]string{
+				EnvName(s.Name()): strings.Join(addrs, ","),
+			},
+		}
+		if s.cdi.Mode.DeviceSpec() {
+			cres.Devices = []*v1beta1.DeviceSpec{
+				{
+					ContainerPath: vfioPath,
+					HostPath:      vfioPath,
+					Permissions:   rwPerm,
+				},
+			}
+			for _, id := range creq.DevicesIDs {
+				cres.Devices = append(cres.Devices, &v1beta1.DeviceSpec{
+					ContainerPath: groupPath(id),
+					HostPath:      groupPath(id),
+					Permissions:   rwPerm,
+				})
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfiodeviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeDev creates a /dev tree with the VFIO container node and the group nodes as
+// regular files.
+func fakeDev(t *testing.T, nodes ...string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "vfio"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range nodes {
+		if err := os.WriteFile(filepath.Join(root, "vfio", node), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// existsChecker accepts any existing file, as device nodes can not be created in tests.
+var existsChecker = healthcheck.CheckerFunc(func(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return healthcheck.OpenError(err)
+	}
+	return nil
+})
+
+func health(devs []*v1beta1.Device) map[string]string {
+	m := map[string]string{}
+	for _, dev := range devs {
+		m[dev.ID] = dev.Health
+	}
+	return m
+}
+
+func newTestServer(t *testing.T, sysfsRoot, devRoot string) *Server {
+	t.Helper()
+
+	s := New("devices.anza-labs.dev", "x710", []ID{x710}, sysfsRoot, devRoot,
+		cdi.Config{Mode: cdi.ModeDeviceSpec}, existsChecker, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+	return s
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := fakeSysfs(t, map[uint][]fakePCI{
+		1: {{address: "0000:03:00.0", id: x710, driver: vfioDriver}},
+		2: {
+			{address: "0000:04:00.0", id: x710, driver: vfioDriver},
+			{address: "0000:04:00.1", id: x710, driver: "i40e"},
+		},
+		3: {{address: "0000:05:00.0", id: x710, driver: vfioDriver}},
+		4: {{address: "0000:06:00.0", id: bridge, driver: vfioDriver}},
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		t.Parallel()
+
+		// the node of group 3 is missing
+		s := newTestServer(t, sysfsRoot, fakeDev(t, "vfio", "1", "2", "4"))
+
+		want := map[string]string{
+			"1": v1beta1.Healthy,
+			"2": v1beta1.Unhealthy, // not viable
+			"3": v1beta1.Unhealthy,
+		}
+		got := health(s.Advertised())
+		if len(got) != len(want) {
+			t.Fatalf("advertised %v, want %v", got, want)
+		}
+		for id, h := range want {
+			if got[id] != h {
+				t.Errorf("group %s is %q, want %q", id, got[id], h)
+			}
+		}
+	})
+
+	t.Run("missing container node", func(t *testing.T) {
+		t.Parallel()
+
+		s := newTestServer(t, sysfsRoot, fakeDev(t, "1", "2", "3"))
+		for id, h := range health(s.Advertised()) {
+			if h != v1beta1.Unhealthy {
+				t.Errorf("group %s is %q without /dev/vfio/vfio, want %q", id, h, v1beta1.Unhealthy)
+			}
+		}
+	})
+
+	t.Run("watch paths", func(t *testing.T) {
+		t.Parallel()
+
+		devRoot := fakeDev(t)
+		s := newTestServer(t, sysfsRoot, devRoot)
+		if got := s.WatchPaths(); len(got) != 1 || got[0] != filepath.Join(devRoot, "vfio", "*") {
+			t.Errorf("WatchPaths() = %v, want the nodes below %s", got, devRoot)
+		}
+	})
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := fakeSysfs(t, map[uint][]fakePCI{
+		1: {{address: "0000:03:00.0", id: x710, driver: vfioDriver}},
+		7: {
+			{address: "0000:04:00.1", id: x710, driver: vfioDriver},
+			{address: "0000:04:00.0", id: x710, driver: vfioDriver},
+		},
+	})
+	s := newTestServer(t, sysfsRoot, fakeDev(t, "vfio", "1", "7"))
+
+	res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"1", "7"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cres := res.ContainerResponses[0]
+	env := EnvName("devices.anza-labs.dev/x710")
+	if got, want := cres.Envs[env], "0000:03:00.0,0000:04:00.0,0000:04:00.1"; got != want {
+		t.Errorf("%s = %q, want %q", env, got, want)
+	}
+
+	// containers get the host paths, regardless of where /dev is mounted in the plugin
+	want := []string{"/dev/vfio/vfio", "/dev/vfio/1", "/dev/vfio/7"}
+	if len(cres.Devices) != len(want) {
+		t.Fatalf("Devices = %v, want %v", cres.Devices, want)
+	}
+	for i, dev := range cres.Devices {
+		if dev.HostPath != want[i] || dev.ContainerPath != want[i] {
+			t.Errorf("Devices[%d] = %s:%s, want %s", i, dev.HostPath, dev.ContainerPath, want[i])
+		}
+	}
+}
+
+func TestServers(t *testing.T) {
+	t.Parallel()
+
+	e810 := ID{Vendor: 0x8086, Device: 0x1592}
+	sysfsRoot := fakeSysfs(t, map[uint][]fakePCI{
+		1: {{address: "0000:03:00.0", id: x710, driver: vfioDriver}},
+		2: {{address: "0000:04:00.0", id: e810, driver: vfioDriver}},
+		3: {{address: "0000:05:00.0", id: bridge, driver: "pcieport"}},
+	})
+	aliases := Aliases{x710: "nic", ID{Vendor: 0x8086, Device: 0x158b}: "nic"}
+
+	servers, err := Servers("devices.anza-labs.dev", sysfsRoot, fakeDev(t), aliases, cdi.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, s := range servers {
+			s.Close() //nolint:errcheck // best effort call
+		}
+	})
+
+	want := []string{"devices.anza-labs.dev/nic", "devices.anza-labs.dev/pci-8086-1592"}
+	if len(servers) != len(want) {
+		t.Fatalf("got %d servers, want %v", len(servers), want)
+	}
+	for i, s := range servers {
+		if s.Name() != want[i] {
+			t.Errorf("servers[%d] = %s, want %s", i, s.Name(), want[i])
+		}
+	}
+	if len(servers[0].ids) != 2 {
+		t.Errorf("ids of %s = %v, want both aliased IDs", servers[0].Name(), servers[0].ids)
+	}
+}
+
+func TestDefaultHealthChecker(t *testing.T) {
+	t.Parallel()
+
+	devRoot := fakeDev(t, "vfio", "1")
+	for node, reason := range map[string]string{
+		"vfio": healthcheck.ReasonNotCharDevice,
+		"1":    healthcheck.ReasonNotCharDevice,
+		"2":    healthcheck.ReasonNotFound,
+	} {
+		err := DefaultHealthChecker.Check(filepath.Join(devRoot, "vfio", node))
+		if got := healthcheck.Reason(err); err == nil || got != reason {
+			t.Errorf("Check(%s) = %v, want reason %q", node, err, reason)
+		}
+	}
+}
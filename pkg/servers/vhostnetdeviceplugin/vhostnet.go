@@ -0,0 +1,118 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vhostnetdeviceplugin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	vhostNetPath   = "/dev/vhost-net"
+	vhostNetName   = "vhost-net"
+	vhostNetModule = "/sys/module/vhost_net"
+
+	vhostNetMajor = 10
+	vhostNetMinor = 238
+
+	reasonModuleNotLoaded = "module_not_loaded"
+)
+
+// DefaultHealthChecker verifies that /dev/vhost-net is the vhost-net misc device and
+// that it can be opened. Opening the device also loads the vhost_net module on demand.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.CharDevice(vhostNetMajor, vhostNetMinor),
+	healthcheck.Open(unix.O_RDWR),
+)
+
+type Server struct {
+	*plugin.NodeServer
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the vhost-net device plugin server. If checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	devices uint,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	return newServer(vhostNetPath, vhostNetModule, namespace, devices, cdiConfig, checker, log)
+}
+
+func newServer(
+	devPath, modulePath string,
+	namespace string,
+	devices uint,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	return &Server{
+		NodeServer: plugin.NewNodeServer(plugin.NodeConfig{
+			Namespace: namespace,
+			Name:      vhostNetName,
+			Kind:      "vhost-net",
+			Path:      devPath,
+			Devices:   devices,
+			Checker:   checker,
+			Missing:   moduleCheck(modulePath),
+		}, cdiConfig, log),
+	}
+}
+
+// moduleCheck returns an error if the vhost_net module is not loaded. The module creates
+// /dev/vhost-net and is loaded on demand by opening it, so a missing device node is only
+// reported as unhealthy when the module is not loaded either.
+func moduleCheck(modulePath string) func() error {
+	return func() error {
+		_, err := os.Stat(modulePath)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, fs.ErrNotExist):
+			return &healthcheck.Error{
+				Reason: reasonModuleNotLoaded,
+				Err:    fmt.Errorf("vhost_net module is not loaded: %w", err),
+			}
+		default:
+			return &healthcheck.Error{
+				Reason: healthcheck.ReasonProbeFailed,
+				Err:    fmt.Errorf("failed to stat %s: %w", modulePath, err),
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vhostnetdeviceplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthy accepts any device node, as device nodes can not be created in tests.
+var healthy = healthcheck.CheckerFunc(func(string) error { return nil })
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		node   bool
+		module bool
+		want   []string
+	}{
+		{name: "present", node: true, want: []string{v1beta1.Healthy, v1beta1.Healthy}},
+		{name: "module loaded without node", module: true},
+		{name: "module not loaded", want: []string{v1beta1.Unhealthy, v1beta1.Unhealthy}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			devPath := filepath.Join(dir, "vhost-net")
+			modulePath := filepath.Join(dir, "vhost_net")
+			if tc.node {
+				if err := os.WriteFile(devPath, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.module {
+				if err := os.Mkdir(modulePath, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s := newServer(devPath, modulePath, "devices.anza-labs.dev", 2,
+				cdi.Config{Mode: cdi.ModeDeviceSpec}, healthy, nil)
+			t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+			devs := s.Advertised()
+			if len(devs) != len(tc.want) {
+				t.Fatalf("advertised %v, want %d devices", devs, len(tc.want))
+			}
+			for i, dev := range devs {
+				if dev.Health != tc.want[i] {
+					t.Errorf("health of %s = %s, want %s", dev.ID, dev.Health, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestModuleCheck(t *testing.T) {
+	t.Parallel()
+
+	err := moduleCheck(filepath.Join(t.TempDir(), "vhost_net"))()
+	if reason := healthcheck.Reason(err); reason != reasonModuleNotLoaded {
+		t.Errorf("Reason() = %q, want %q", reason, reasonModuleNotLoaded)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error %v does not wrap os.ErrNotExist", err)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	devPath := filepath.Join(t.TempDir(), "vhost-net")
+	if err := os.WriteFile(devPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(devPath, vhostNetModule, "devices.anza-labs.dev", 2,
+		cdi.Config{Mode: cdi.ModeBoth, SpecDir: t.TempDir()}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+
+	res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"vhost-net1"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cres := res.ContainerResponses[0]
+	if len(cres.Devices) != 1 || cres.Devices[0].HostPath != devPath {
+		t.Errorf("Devices = %v, want %s", cres.Devices, devPath)
+	}
+	name := cdi.QualifiedName("devices.anza-labs.dev/vhost-net", "vhost-net1")
+	if len(cres.CDIDevices) != 1 || cres.CDIDevices[0].Name != name {
+		t.Errorf("CDI devices = %v, want %s", cres.CDIDevices, name)
+	}
+}
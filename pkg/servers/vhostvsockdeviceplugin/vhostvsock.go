@@ -0,0 +1,364 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vhostvsockdeviceplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/discovery"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/metrics"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/plugin"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	vhostVsockPath   = "/dev/vhost-vsock"
+	vhostVsockName   = "vhost-vsock"
+	vhostVsockModule = "/sys/module/vhost_vsock"
+	rwPerm           = "rw"
+
+	vhostVsockMajor = 10
+	vhostVsockMinor = 241
+
+	// CIDEnv is the environment variable holding the guest CIDs allocated to a container,
+	// separated by commas if more than one device was requested.
+	CIDEnv = "VSOCK_GUEST_CID"
+
+	// minCID is the lowest guest CID, lower CIDs are reserved for the hypervisor, the
+	// local loopback and the host.
+	minCID = 3
+	// maxCID is the highest guest CID, higher CIDs are reserved (VMADDR_CID_ANY).
+	maxCID = 0xFFFFFFFE
+	// maxCIDs limits the size of the pool, as every CID is advertised to kubelet.
+	maxCIDs = 4096
+)
+
+// DefaultCIDRange is the default pool of guest CIDs.
+var DefaultCIDRange = CIDRange{First: 3, Last: 102}
+
+// DefaultHealthChecker verifies that /dev/vhost-vsock is the vhost-vsock misc device and
+// that it can be opened. Opening the device also loads the vhost_vsock module on demand.
+var DefaultHealthChecker = healthcheck.All(
+	healthcheck.CharDevice(vhostVsockMajor, vhostVsockMinor),
+	healthcheck.Open(unix.O_RDWR),
+)
+
+// CIDRange is an inclusive range of guest CIDs. Every CID is advertised as a device, so
+// kubelet never allocates a CID to two containers at the same time.
+type CIDRange struct {
+	First uint32
+	Last  uint32
+}
+
+// ParseCIDRange parses a range of guest CIDs in the form "first-last".
+func ParseCIDRange(s string) (CIDRange, error) {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		return CIDRange{}, fmt.Errorf("invalid CID range %q, expected first-last", s)
+	}
+
+	f, err := strconv.ParseUint(strings.TrimSpace(first), 10, 32)
+	if err != nil {
+		return CIDRange{}, fmt.Errorf("invalid CID range %q: %w", s, err)
+	}
+	l, err := strconv.ParseUint(strings.TrimSpace(last), 10, 32)
+	if err != nil {
+		return CIDRange{}, fmt.Errorf("invalid CID range %q: %w", s, err)
+	}
+
+	r := CIDRange{First: uint32(f), Last: uint32(l)}
+	if err := r.Validate(); err != nil {
+		return CIDRange{}, err
+	}
+	return r, nil
+}
+
+// Validate checks that the range is not empty, contains only guest CIDs and is not too large.
+func (r CIDRange) Validate() error {
+	switch {
+	case r.First > r.Last:
+		return fmt.Errorf("invalid CID range %s, first CID is greater than last", r)
+	case r.First < minCID:
+		return fmt.Errorf("invalid CID range %s, guest CIDs start at %d", r, minCID)
+	case r.Last > maxCID:
+		return fmt.Errorf("invalid CID range %s, guest CIDs end at %d", r, uint32(maxCID))
+	case r.Last-r.First >= maxCIDs:
+		return fmt.Errorf("invalid CID range %s, at most %d CIDs are supported", r, maxCIDs)
+	}
+	return nil
+}
+
+func (r CIDRange) String() string {
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+type Server struct {
+	plugin.Base
+
+	log       *slog.Logger
+	path      string
+	namespace string
+	cids      CIDRange
+	cdi       cdi.Config
+	checker   healthcheck.Checker
+
+	mu     sync.RWMutex
+	module bool
+}
+
+var (
+	_ v1beta1.DevicePluginServer = (*Server)(nil)
+	_ discovery.DiscoverUpdater  = (*Server)(nil)
+	_ discovery.Watcher          = (*Server)(nil)
+)
+
+// New creates the vhost-vsock device plugin server, advertising a device for every guest
+// CID in cids. If checker is nil, DefaultHealthChecker is used.
+func New(
+	namespace string,
+	cids CIDRange,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	return newServer(vhostVsockPath, namespace, cids, cdiConfig, checker, log)
+}
+
+func newServer(
+	devPath string,
+	namespace string,
+	cids CIDRange,
+	cdiConfig cdi.Config,
+	checker healthcheck.Checker,
+	log *slog.Logger,
+) *Server {
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+	if checker == nil {
+		checker = DefaultHealthChecker
+	}
+	s := &Server{
+		log:       log,
+		path:      devPath,
+		namespace: namespace,
+		cids:      cids,
+		cdi:       cdiConfig,
+		checker:   checker,
+	}
+	s.Base = plugin.NewBase(s.Name(), s.cdi, s.log)
+	if err := s.Discover(); err != nil {
+		log.Error("Initial discovery failed", "error", err)
+	}
+	s.Update()
+	if len(s.Devices()) == 0 {
+		log.Warn("No vhost-vsock device found", "module", moduleLoaded())
+	}
+	return s
+}
+
+// Discover checks whether the vhost-vsock device is present and healthy and rebuilds the
+// list of advertised devices. Changes are published to kubelet on the next call to Update.
+func (s *Server) Discover() error {
+	var herr error
+	devs := []*v1beta1.Device{}
+
+	_, err := os.Stat(s.path)
+	switch {
+	case err == nil:
+		health := v1beta1.Healthy
+		if herr = s.checker.Check(s.path); herr != nil {
+			health = v1beta1.Unhealthy
+		}
+
+		for cid := uint64(s.cids.First); cid <= uint64(s.cids.Last); cid++ {
+			devs = append(devs, &v1beta1.Device{
+				ID:     deviceID(uint32(cid)),
+				Health: health,
+			})
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		// device is not present (yet), advertise no devices
+	default:
+		return fmt.Errorf("failed to stat %s: %w", s.path, err)
+	}
+
+	module := moduleLoaded()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if module != s.module {
+		s.log.Info("Detected vhost_vsock module", "loaded", module)
+		s.module = module
+	}
+
+	if plugin.EqualDevices(devs, s.Devices()) {
+		return nil
+	}
+
+	switch {
+	case len(devs) == 0:
+		s.log.Warn("vhost-vsock device disappeared", "module", module)
+	case herr != nil:
+		reason := healthcheck.Reason(herr)
+		s.log.Warn("vhost-vsock device is unhealthy", "reason", reason, "error", herr)
+		metrics.UnhealthyTransitions.WithLabelValues(s.Name(), reason).Inc()
+	case len(s.Devices()) == 0:
+		s.log.Info("Discovered vhost-vsock device", "cids", s.cids.String(), "module", module)
+	default:
+		s.log.Info("vhost-vsock device is healthy")
+	}
+
+	s.SetDevices(devs)
+	return nil
+}
+
+// Update publishes the device list to ListAndWatch streams if it changed since the last call.
+func (s *Server) Update() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sync(s.cdiSpec)
+}
+
+func (s *Server) cdiSpec() *cdi.Spec {
+	spec := &cdi.Spec{Kind: s.Name()}
+	for _, dev := range s.Devices() {
+		spec.Devices = append(spec.Devices, cdi.Device{
+			Name: dev.ID,
+			ContainerEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        s.path,
+						HostPath:    s.path,
+						Permissions: rwPerm,
+					},
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// WatchPaths returns the device node paths watched for changes by discovery.
+func (s *Server) WatchPaths() []string {
+	return []string{s.path}
+}
+
+// moduleLoaded reports whether the vhost_vsock module is loaded. The module is loaded on
+// demand by opening /dev/vhost-vsock, so a missing module alone does not make the device
+// unavailable.
+func moduleLoaded() bool {
+	_, err := os.Stat(vhostVsockModule)
+	return err == nil
+}
+
+// deviceID returns the ID of the device of a guest CID.
+func deviceID(cid uint32) string {
+	return fmt.Sprintf("cid%d", cid)
+}
+
+// parseDeviceID returns the guest CID of a device ID.
+func parseDeviceID(id string) (uint32, error) {
+	n, ok := strings.CutPrefix(id, "cid")
+	if !ok {
+		return 0, fmt.Errorf("invalid device ID %q", id)
+	}
+	cid, err := strconv.ParseUint(n, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid device ID %q", id)
+	}
+	return uint32(cid), nil
+}
+
+func (s *Server) Name() string {
+	return path.Join(s.namespace, vhostVsockName)
+}
+
+func (s *Server) Socket() string {
+	return fmt.Sprintf("unix://%s", path.Join(v1beta1.DevicePluginPath, vhostVsockName+".sock"))
+}
+
+func (s *Server) Allocate(
+	ctx context.Context,
+	req *v1beta1.AllocateRequest,
+) (*v1beta1.AllocateResponse, error) {
+	if err := s.CheckAllocation(req); err != nil {
+		return nil, err
+	}
+
+	res := &v1beta1.AllocateResponse{
+		ContainerResponses: make([]*v1beta1.ContainerAllocateResponse, 0, len(req.ContainerRequests)),
+	}
+
+	// kubelet never assigns a device to more than one container at a time, so device IDs
+	// map to node-unique CIDs; a CID repeated within the request is rejected nonetheless
+	seen := map[uint32]struct{}{}
+	for _, creq := range req.ContainerRequests {
+		cids := make([]string, 0, len(creq.DevicesIDs))
+		for _, id := range creq.DevicesIDs {
+			cid, err := parseDeviceID(id)
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			if _, ok := seen[cid]; ok {
+				return nil, status.Errorf(codes.InvalidArgument, "guest CID %d requested more than once", cid)
+			}
+			seen[cid] = struct{}{}
+			cids = append(cids, strconv.FormatUint(uint64(cid), 10))
+		}
+
+		// CIDs are passed as environment variable in all device modes, as CDI specs of
+		// multiple devices can not set different values of the same variable
+		cres := &v1beta1.ContainerAllocateResponse{
+			Envs: map[string]string{
+				CIDEnv: strings.Join(cids, ","),
+			},
+		}
+		if s.cdi.Mode.DeviceSpec() {
+			cres.Devices = []*v1beta1.DeviceSpec{
+				{
+					ContainerPath: s.path,
+					HostPath:      s.path,
+					Permissions:   rwPerm,
+				},
+			}
+		}
+		if s.cdi.Mode.CDI() {
+			cres.CDIDevices = cdi.Devices(s.Name(), creq.DevicesIDs)
+		}
+
+		res.ContainerResponses = append(res.ContainerResponses, cres)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vhostvsockdeviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/anza-labs/kubelet-device-plugins/pkg/cdi"
+	"github.com/anza-labs/kubelet-device-plugins/pkg/healthcheck"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// healthy accepts any device node, as device nodes can not be created in tests.
+var healthy = healthcheck.CheckerFunc(func(string) error { return nil })
+
+func TestParseCIDRange(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  CIDRange
+		err   string
+	}{
+		{name: "default", value: "3-102", want: CIDRange{First: 3, Last: 102}},
+		{name: "single", value: "10-10", want: CIDRange{First: 10, Last: 10}},
+		{name: "spaces", value: " 3 - 4 ", want: CIDRange{First: 3, Last: 4}},
+		{name: "largest", value: "100-4195", want: CIDRange{First: 100, Last: 4195}},
+		{name: "missing dash", value: "3", err: "expected first-last"},
+		{name: "empty", value: "", err: "expected first-last"},
+		{name: "not a number", value: "a-10", err: "invalid CID range"},
+		{name: "negative", value: "3--1", err: "invalid CID range"},
+		{name: "overflow", value: "3-4294967296", err: "invalid CID range"},
+		{name: "reversed", value: "10-3", err: "first CID is greater than last"},
+		{name: "host", value: "2-10", err: "guest CIDs start at 3"},
+		{name: "hypervisor", value: "0-10", err: "guest CIDs start at 3"},
+		{name: "any", value: "4294967290-4294967295", err: "guest CIDs end at"},
+		{name: "too large", value: "3-4099", err: "at most 4096 CIDs"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseCIDRange(tc.value)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			}
+			if got != tc.want {
+				t.Errorf("ParseCIDRange(%q) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCIDRangeValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		r    CIDRange
+		err  string
+	}{
+		{name: "default", r: DefaultCIDRange},
+		{name: "lowest", r: CIDRange{First: minCID, Last: minCID}},
+		{name: "highest", r: CIDRange{First: maxCID, Last: maxCID}},
+		{name: "largest", r: CIDRange{First: minCID, Last: minCID + maxCIDs - 1}},
+		{name: "reversed", r: CIDRange{First: 5, Last: 4}, err: "first CID is greater than last"},
+		{name: "below minimum", r: CIDRange{First: minCID - 1, Last: 10}, err: "guest CIDs start at"},
+		{name: "above maximum", r: CIDRange{First: maxCID, Last: maxCID + 1}, err: "guest CIDs end at"},
+		{name: "too large", r: CIDRange{First: minCID, Last: minCID + maxCIDs}, err: "at most 4096 CIDs"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.r.Validate()
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("Validate() = %v, want error containing %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func newTestServer(t *testing.T, cids CIDRange) *Server {
+	t.Helper()
+
+	devPath := filepath.Join(t.TempDir(), "vhost-vsock")
+	if err := os.WriteFile(devPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(devPath, "devices.anza-labs.dev", cids,
+		cdi.Config{Mode: cdi.ModeBoth, SpecDir: t.TempDir()}, healthy, nil)
+	t.Cleanup(func() { s.Close() }) //nolint:errcheck // best effort call
+	return s
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t, CIDRange{First: 3, Last: 5})
+
+	devs := s.Advertised()
+	ids := make([]string, 0, len(devs))
+	for _, dev := range devs {
+		ids = append(ids, dev.ID)
+	}
+	if got, want := strings.Join(ids, ","), "cid3,cid4,cid5"; got != want {
+		t.Errorf("advertised %s, want %s", got, want)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t, CIDRange{First: 3, Last: 10})
+
+	res, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{
+			{DevicesIDs: []string{"cid7"}},
+			{DevicesIDs: []string{"cid10", "cid3"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"7", "10,3"} {
+		cres := res.ContainerResponses[i]
+		if got := cres.Envs[CIDEnv]; got != want {
+			t.Errorf("%s of container %d = %q, want %q", CIDEnv, i, got, want)
+		}
+		if len(cres.Devices) != 1 || cres.Devices[0].HostPath != s.path {
+			t.Errorf("Devices of container %d = %v, want %s", i, cres.Devices, s.path)
+		}
+	}
+	if got := res.ContainerResponses[1].CDIDevices; len(got) != 2 ||
+		got[0].Name != cdi.QualifiedName(s.Name(), "cid10") || got[1].Name != cdi.QualifiedName(s.Name(), "cid3") {
+		t.Errorf("CDI devices = %v, want cid10 and cid3", got)
+	}
+}
+
+func TestAllocateErrors(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t, CIDRange{First: 3, Last: 10})
+
+	for _, tc := range []struct {
+		name string
+		reqs []*v1beta1.ContainerAllocateRequest
+		code codes.Code
+	}{
+		{
+			name: "duplicate in container",
+			reqs: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"cid4", "cid4"}}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "duplicate across containers",
+			reqs: []*v1beta1.ContainerAllocateRequest{
+				{DevicesIDs: []string{"cid4"}},
+				{DevicesIDs: []string{"cid5", "cid4"}},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "outside of range",
+			reqs: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"cid11"}}},
+			code: codes.NotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := s.Allocate(context.Background(), &v1beta1.AllocateRequest{ContainerRequests: tc.reqs})
+			if got := status.Code(err); got != tc.code {
+				t.Errorf("Allocate() = %v, want code %v", err, tc.code)
+			}
+		})
+	}
+}
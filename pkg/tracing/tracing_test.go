@@ -0,0 +1,70 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupDisabled(t *testing.T) {
+	t.Parallel()
+
+	shutdown, err := Setup(t.Context(), "", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := Tracer().Start(t.Context(), "test")
+	if span.IsRecording() {
+		t.Error("span is recorded without endpoint")
+	}
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() = %v, want nil", err)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	t.Parallel()
+
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer(Name)
+
+	_, span := tracer.Start(t.Context(), "success")
+	End(span, nil)
+	_, span = tracer.Start(t.Context(), "failure")
+	End(span, errors.New("failed"))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if got := spans[0].Status().Code; got != codes.Unset {
+		t.Errorf("status of successful span = %v, want %v", got, codes.Unset)
+	}
+	if got := spans[1].Status(); got.Code != codes.Error || got.Description != "failed" {
+		t.Errorf("status of failed span = %v, want %v: failed", got, codes.Error)
+	}
+	if events := spans[1].Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("events of failed span = %v, want recorded error", events)
+	}
+}